@@ -0,0 +1,194 @@
+package main
+
+import (
+	"cacao/handlers"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ─── Sous-commandes (maintenance / sauvegarde) ─────────────────────────────
+//
+//	cacao                           serveur HTTP (défaut)
+//	cacao serve                     idem
+//	cacao export --out backup.json  sauvegarde JSON ("-" : sortie standard)
+//	cacao import --in backup.json   restauration ("-" : entrée standard, --dry-run : aperçu)
+//	cacao prune-aromas              retire les ids d'arômes supprimés des fiches
+//	cacao regenerate-photos         variantes/blurhash des anciennes photos (--limit N)
+//	cacao geocode-cities            coordonnées des fiches avec ville seule (--limit N --after VILLE)
+
+// Délai max d'une sous-commande (grosse base + réseau lent)
+const commandTimeout = 5 * time.Minute
+
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"export":            exportCommand,
+	"import":            importCommand,
+	"prune-aromas":      pruneAromasCommand,
+	"regenerate-photos": regeneratePhotosCommand,
+	"geocode-cities":    geocodeCitiesCommand,
+}
+
+// parseCommand sépare la sous-commande de ses arguments ("serve" par défaut).
+func parseCommand(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "serve", nil
+	}
+	return args[0], args[1:]
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cacao [serve | export --out FICHIER | import --in FICHIER [--dry-run] | prune-aromas | regenerate-photos [--limit N] | geocode-cities [--limit N] [--after VILLE]]")
+}
+
+// runCommand exécute une sous-commande (DB déjà connectée) et renvoie le code de sortie.
+func runCommand(name string, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	if err := commands[name](ctx, args); err != nil {
+		log.Printf("❌ %s: %v", name, err)
+		return 1
+	}
+	return 0
+}
+
+func exportCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", `fichier de sauvegarde ("-" pour la sortie standard)`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("--out obligatoire")
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if *out != "-" {
+		// Fichier temporaire puis rename : pas de sauvegarde tronquée si l'export échoue
+		var err error
+		if f, err = os.Create(*out + ".tmp"); err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		w = f
+	}
+
+	st, err := handlers.ExportBackup(ctx, w)
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), *out); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("✅ Export : %d dégustations, %d arômes, %d collections", st.Tastings, st.Aromas, st.Collections)
+	return nil
+}
+
+func importCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", `fichier de sauvegarde ("-" pour l'entrée standard)`)
+	dryRun := fs.Bool("dry-run", false, "vérifier la sauvegarde sans rien écrire")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("--in obligatoire")
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if *dryRun {
+		st, err := handlers.PreviewBackup(ctx, r)
+		if err != nil {
+			return err
+		}
+		log.Printf("✅ Aperçu : %d dégustations à ajouter (%d déjà présentes), %d collections à créer — rien n'a été écrit", st.Tastings, st.Skipped, st.Collections)
+		return nil
+	}
+
+	st, err := handlers.ImportBackup(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Import : %d dégustations ajoutées (%d déjà présentes), %d collections créées", st.Tastings, st.Skipped, st.Collections)
+	return nil
+}
+
+func pruneAromasCommand(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("argument inattendu : %s", args[0])
+	}
+
+	tastings, ids, err := handlers.PruneOrphanAromaIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Arômes orphelins : %d ids retirés de %d dégustations", ids, tastings)
+	return nil
+}
+
+func regeneratePhotosCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("regenerate-photos", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "nombre max de photos à traiter (0 : toutes)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	st, err := handlers.RegenerateMissingPhotos(ctx, *limit)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Photos : %d régénérées, %d échecs, %d restantes", st.Done, st.Failed, st.Remaining)
+	return nil
+}
+
+func geocodeCitiesCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("geocode-cities", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "nombre max de villes à géocoder (0 : toutes)")
+	after := fs.String("after", "", "reprendre après cette ville (curseur affiché en fin de passage)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	st, err := handlers.GeocodeMissingCities(ctx, *after, *limit)
+	if err != nil {
+		if st.Next != "" {
+			log.Printf("Reprise possible : --after %q", st.Next)
+		}
+		return err
+	}
+
+	log.Printf("✅ Géocodage : %d villes, %d fiches mises à jour, %d ignorées, %d fiches sans coordonnées",
+		st.Cities, st.Updated, st.Skipped, st.Remaining)
+	if st.Next != "" {
+		log.Printf("Suite : --after %q", st.Next)
+	}
+	return nil
+}
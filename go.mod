@@ -3,7 +3,15 @@ module cacao
 go 1.25.0
 
 require (
+	github.com/buckket/go-blurhash v1.1.0
+	github.com/gen2brain/webp v0.5.5
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.11.2
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+	golang.org/x/text v0.40.0
+)
+
+require (
+	github.com/ebitengine/purego v0.8.3 // indirect
+	github.com/tetratelabs/wazero v1.9.0 // indirect
 )
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ─── Auth admin (jeton partagé) ────────────────────────────────────────────
+
+// adminToken lit ADMIN_TOKEN. Vide = routes admin désactivées.
+func adminToken() string {
+	return strings.TrimSpace(os.Getenv("ADMIN_TOKEN"))
+}
+
+// RequireAdmin protège une route admin : "Authorization: Bearer <ADMIN_TOKEN>"
+// ou en-tête "X-Admin-Token".
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := adminToken()
+		if expected == "" {
+			writeError(w, http.StatusForbidden, "forbidden", "admin désactivé (ADMIN_TOKEN absent)")
+			return
+		}
+
+		got := strings.TrimSpace(r.Header.Get("X-Admin-Token"))
+		if got == "" {
+			got = strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		}
+
+		if subtle.ConstantTimeCompare([]byte(got), []byte(expected)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "non autorisé")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ─── Fusion maker / produit ────────────────────────────────────────────────
+
+// Colonnes autorisées pour la fusion (jamais de nom de colonne venant du client)
+var mergeableFields = map[string]string{
+	"maker":        "maker",
+	"product_name": "product_name",
+}
+
+// MergeValues renomme toutes les occurrences d'un maker/produit (insensible à la casse).
+// POST /admin/merge  field=maker&from=valrhona&to=Valrhona
+func MergeValues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	col, ok := mergeableFields[strings.TrimSpace(r.FormValue("field"))]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bad_request", "field doit être maker ou product_name")
+		return
+	}
+
+	from := strings.TrimSpace(r.FormValue("from"))
+	to := strings.TrimSpace(r.FormValue("to"))
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "from et to requis")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var changed int64
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE tastings SET `+col+` = $1 WHERE lower(`+col+`) = lower($2) AND `+col+` <> $1`,
+			to, from,
+		)
+		if err != nil {
+			return err
+		}
+		changed, _ = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		log.Println("Erreur merge:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	log.Printf("Merge %s : %q -> %q (%d fiches)", col, from, to, changed)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":      true,
+		"field":   col,
+		"from":    from,
+		"to":      to,
+		"changed": changed,
+	})
+}
+
+// ─── Stats du pool DB ──────────────────────────────────────────────────────
+
+// DBStats expose sql.DBStats (connexions ouvertes/utilisées/inactives, attentes).
+// GET /admin/db/stats
+func DBStats(w http.ResponseWriter, r *http.Request) {
+	st := DB.Stats()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":                   true,
+		"max_open_connections": st.MaxOpenConnections,
+		"open_connections":     st.OpenConnections,
+		"in_use":               st.InUse,
+		"idle":                 st.Idle,
+		"wait_count":           st.WaitCount,
+		"wait_duration_ms":     st.WaitDuration.Milliseconds(),
+		"max_idle_closed":      st.MaxIdleClosed,
+		"max_idle_time_closed": st.MaxIdleTimeClosed,
+		"max_lifetime_closed":  st.MaxLifetimeClosed,
+	})
+}
+
+// ─── Nettoyage des arômes orphelins ────────────────────────────────────────
+
+// PruneOrphanAromaIDs retire des aroma_ids les ids absents de la table aromas
+// (édition manuelle, import raté). Renvoie le nombre de fiches corrigées et d'ids retirés.
+func PruneOrphanAromaIDs(ctx context.Context) (tastings int, ids int, err error) {
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		// Verrou : pas de suppression d'arôme concurrente entre le comptage et l'UPDATE
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE aromas IN SHARE MODE`); err != nil {
+			return err
+		}
+
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM tastings t
+			CROSS JOIN LATERAL unnest(t.aroma_ids) AS aid
+			WHERE NOT EXISTS (SELECT 1 FROM aromas a WHERE a.id = aid)
+		`).Scan(&ids); err != nil {
+			return err
+		}
+		if ids == 0 {
+			return nil
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE tastings t
+			SET aroma_ids = COALESCE((
+				SELECT array_agg(u.aid ORDER BY u.ord)
+				FROM unnest(t.aroma_ids) WITH ORDINALITY AS u(aid, ord)
+				WHERE EXISTS (SELECT 1 FROM aromas a WHERE a.id = u.aid)
+			), '{}')
+			WHERE EXISTS (
+				SELECT 1 FROM unnest(t.aroma_ids) AS aid
+				WHERE NOT EXISTS (SELECT 1 FROM aromas a WHERE a.id = aid)
+			)
+		`)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		tastings = int(n)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return tastings, ids, nil
+}
+
+// PruneOrphanAromas nettoie les références à des arômes supprimés.
+// POST /admin/aromas/prune
+func PruneOrphanAromas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	tastings, ids, err := PruneOrphanAromaIDs(ctx)
+	if err != nil {
+		log.Println("Erreur nettoyage arômes orphelins:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	log.Printf("Arômes orphelins : %d ids retirés de %d dégustations", ids, tastings)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "tastings": tastings, "aroma_ids": ids})
+}
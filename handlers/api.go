@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,33 +34,97 @@ type ProductSuggestion struct {
 	Maker string `json:"maker"`
 }
 
+// Cache court des suggestions (frappe rapide = mêmes requêtes en rafale)
+const productSuggestTTL = 30 * time.Second
+
+// Nombre de suggestions : ?limit=N (10 par défaut, 25 au plus)
+const (
+	productSuggestLimit    = 10
+	productSuggestMaxLimit = 25
+)
+
+var productSuggestCache = &geoCache{entries: make(map[string]geoCacheEntry)}
+
 func ProductSuggest(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimitParam(r, productSuggestLimit, productSuggestMaxLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
 	q := strings.TrimSpace(r.URL.Query().Get("q"))
 	if len(q) < 2 {
 		writeJSON(w, http.StatusOK, []ProductSuggestion{})
 		return
 	}
 
+	cacheKey := strconv.Itoa(limit) + ":" + strings.ToLower(q)
+	if body, ok := productSuggestCache.get(cacheKey); ok {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(body)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 
-	needle := "%" + q + "%"
+	// 1) Préfixe (peut utiliser un index), 2) complété par la recherche "contient".
+	out := make([]ProductSuggestion, 0, limit)
+	seen := map[ProductSuggestion]bool{}
 
-	rows, err := DB.QueryContext(ctx, `
-		SELECT DISTINCT product_name, COALESCE(maker,'')
-		FROM tastings
-		WHERE product_name ILIKE $1 OR maker ILIKE $1
-		ORDER BY product_name
-		LIMIT 10
-	`, needle)
+	// Terme échappé : un "%" ou "_" tapé par l'utilisateur reste littéral
+	term := escapeLike(q)
+	prefix := term + "%"
+	for _, needle := range []string{prefix, "%" + term + "%"} {
+		if len(out) >= limit {
+			break
+		}
+		found, err := queryProductSuggestions(ctx, needle, prefix, limit)
+		if err != nil {
+			log.Println("Erreur autocomplete:", err)
+			writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+			return
+		}
+		for _, s := range found {
+			if seen[s] || len(out) >= limit {
+				continue
+			}
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+
+	body, err := json.Marshal(out)
 	if err != nil {
-		log.Println("Erreur autocomplete:", err)
-		writeJSON(w, http.StatusOK, []ProductSuggestion{})
+		writeJSON(w, http.StatusOK, out)
 		return
 	}
+	productSuggestCache.set(cacheKey, body, productSuggestTTL)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(body)
+}
+
+// queryProductSuggestions cherche `needle` (motif ILIKE déjà échappé, voir escapeLike) dans product_name/maker.
+// Classement : nom commençant par `prefix` d'abord, puis noms courts, puis alphabétique.
+// Insensible aux accents (f_unaccent) ; les noms sont renvoyés tels quels, accents compris.
+// Index trigram : voir migrations/006_unaccent_search.sql.
+func queryProductSuggestions(ctx context.Context, needle, prefix string, limit int) ([]ProductSuggestion, error) {
+	rows, err := queryContext(ctx, "products.suggest", `
+		SELECT product_name, COALESCE(maker,'')
+		FROM tastings
+		WHERE f_unaccent(product_name) ILIKE f_unaccent($1) ESCAPE '\'
+		   OR f_unaccent(maker) ILIKE f_unaccent($1) ESCAPE '\'
+		GROUP BY product_name, COALESCE(maker,'')
+		ORDER BY (f_unaccent(product_name) ILIKE f_unaccent($2) ESCAPE '\') DESC, length(product_name), product_name
+		LIMIT $3
+	`, needle, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	out := make([]ProductSuggestion, 0, 10)
+	var out []ProductSuggestion
 	for rows.Next() {
 		var s ProductSuggestion
 		if err := rows.Scan(&s.Name, &s.Maker); err != nil {
@@ -68,12 +136,12 @@ func ProductSuggest(w http.ResponseWriter, r *http.Request) {
 			out = append(out, s)
 		}
 	}
-
-	writeJSON(w, http.StatusOK, out)
+	return out, rows.Err()
 }
 
 // ─── Geo proxy (cache simple en mémoire) ───────────────────────────────────
 
+// Les entrées expirées sont purgées par le planificateur (voir scheduler.go).
 type geoCache struct {
 	mu      sync.RWMutex
 	entries map[string]geoCacheEntry
@@ -86,9 +154,6 @@ type geoCacheEntry struct {
 
 var geoCache_ = &geoCache{entries: make(map[string]geoCacheEntry)}
 
-// Nettoyage opportuniste : toutes les X écritures, on vire les entrées expirées
-var geoCacheSetCount int
-
 func (c *geoCache) get(key string) ([]byte, bool) {
 	c.mu.RLock()
 	e, ok := c.entries[key]
@@ -103,13 +168,7 @@ func (c *geoCache) get(key string) ([]byte, bool) {
 func (c *geoCache) set(key string, body []byte, ttl time.Duration) {
 	c.mu.Lock()
 	c.entries[key] = geoCacheEntry{body: body, expiresAt: time.Now().Add(ttl)}
-	geoCacheSetCount++
-	doCleanup := geoCacheSetCount%50 == 0
 	c.mu.Unlock()
-
-	if doCleanup {
-		c.cleanupExpired()
-	}
 }
 
 func (c *geoCache) cleanupExpired() {
@@ -123,8 +182,9 @@ func (c *geoCache) cleanupExpired() {
 	c.mu.Unlock()
 }
 
+// Client HTTP géocodage / Open Food Facts
 var geoHTTPClient = &http.Client{
-	Timeout: 6 * time.Second,
+	Timeout: DefaultGeoTimeout,
 }
 
 func nominatimUserAgent() string {
@@ -140,17 +200,16 @@ func nominatimEmailParam() string {
 	return strings.TrimSpace(os.Getenv("NOMINATIM_EMAIL"))
 }
 
-func nominatimProxy(nominatimURL string, w http.ResponseWriter, r *http.Request) {
+// fetchNominatim interroge Nominatim (User-Agent obligatoire) ; réponses OK en cache 24 h.
+// Partagé par le proxy géo et le géocodage des fiches (geobackfill.go).
+func fetchNominatim(ctx context.Context, nominatimURL string) ([]byte, error) {
 	if body, ok := geoCache_.get(nominatimURL); ok {
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		_, _ = w.Write(body)
-		return
+		return body, nil
 	}
 
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, nominatimURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, nominatimURL, nil)
 	if err != nil {
-		http.Error(w, "Erreur requête geo", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	req.Header.Set("User-Agent", nominatimUserAgent())
@@ -159,67 +218,139 @@ func nominatimProxy(nominatimURL string, w http.ResponseWriter, r *http.Request)
 
 	resp, err := geoHTTPClient.Do(req)
 	if err != nil {
-		http.Error(w, "Service géolocalisation indisponible", http.StatusBadGateway)
-		return
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		http.Error(w, "Erreur lecture réponse geo", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	// Cache seulement si OK et non vide
-	if resp.StatusCode == http.StatusOK && len(body) > 0 {
-		geoCache_.set(nominatimURL, body, 24*time.Hour)
+	// Erreur amont (quota, panne) : jamais mise en cache
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpError{Status: resp.Status, Body: string(body)}
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(resp.StatusCode)
-	_, _ = w.Write(body)
+	// Cache seulement si non vide
+	if len(body) > 0 {
+		geoCache_.set(nominatimURL, body, 24*time.Hour)
+	}
+	return body, nil
 }
 
-// GeoSearch proxifie la recherche Nominatim.
-// GET /api/geo/search?q=Paris
-func GeoSearch(w http.ResponseWriter, r *http.Request) {
-	q := strings.TrimSpace(r.URL.Query().Get("q"))
-	if len(q) < 2 {
-		writeEmptyArray(w)
+func nominatimProxy(nominatimURL string, w http.ResponseWriter, r *http.Request) {
+	body, err := fetchNominatim(r.Context(), nominatimURL)
+	if err != nil {
+		log.Printf("Nominatim: %.200s", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "Service géolocalisation indisponible")
 		return
 	}
 
-	base := "https://nominatim.openstreetmap.org/search"
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(body)
+}
+
+// nominatimSearchURL construit une recherche Nominatim (limit résultats, adresse détaillée).
+// countryCodes ("fr,be", "" : monde entier) : déjà validé par parseCountryCodes.
+func nominatimSearchURL(q string, limit int, countryCodes string) string {
 	v := url.Values{}
 	v.Set("format", "json")
 	v.Set("q", q)
-	v.Set("limit", "6")
+	v.Set("limit", strconv.Itoa(limit))
 	v.Set("addressdetails", "1")
 	v.Set("accept-language", "fr")
+	if countryCodes != "" {
+		v.Set("countrycodes", countryCodes)
+	}
 	if em := nominatimEmailParam(); em != "" {
 		v.Set("email", em)
 	}
+	return "https://nominatim.openstreetmap.org/search?" + v.Encode()
+}
+
+const (
+	geoSearchLimit    = 6
+	geoSearchMaxLimit = 20
+	maxCountryCodes   = 10
+)
+
+// Codes pays ISO 3166-1 alpha-2 séparés par des virgules
+var countryCodesRe = regexp.MustCompile(`^[a-z]{2}(,[a-z]{2})*$`)
+
+// geoCountryCodes : filtre pays par défaut des recherches de lieux (GEO_COUNTRYCODES)
+var geoCountryCodes string
+
+// parseCountryCodes normalise "FR, be" en "fr,be" ("" reste "").
+func parseCountryCodes(s string) (string, error) {
+	s = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if s == "" {
+		return "", nil
+	}
+	if !countryCodesRe.MatchString(s) || strings.Count(s, ",") >= maxCountryCodes {
+		return "", fmt.Errorf("countrycodes invalide : %q (codes ISO à 2 lettres séparés par des virgules, ex : fr,be ; %d max)", s, maxCountryCodes)
+	}
+	return s, nil
+}
 
-	nominatimProxy(base+"?"+v.Encode(), w, r)
+// SetGeoCountryCodes lit GEO_COUNTRYCODES ("fr,be,ch" ; vide : monde entier).
+// En cas d'erreur, le filtre courant est conservé.
+func SetGeoCountryCodes(s string) error {
+	cc, err := parseCountryCodes(s)
+	if err != nil {
+		return fmt.Errorf("GEO_COUNTRYCODES : %w", err)
+	}
+	geoCountryCodes = cc
+	return nil
+}
+
+// GeoSearch proxifie la recherche Nominatim. countrycodes absent : GEO_COUNTRYCODES ;
+// countrycodes= (vide) : monde entier. L'URL complète sert de clé de cache.
+// GET /api/v1/geo/search?q=Paris[&limit=10][&countrycodes=fr,be]
+func GeoSearch(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimitParam(r, geoSearchLimit, geoSearchMaxLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	countryCodes := geoCountryCodes
+	if r.URL.Query().Has("countrycodes") {
+		if countryCodes, err = parseCountryCodes(r.URL.Query().Get("countrycodes")); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(q) < 2 {
+		writeEmptyArray(w)
+		return
+	}
+
+	nominatimProxy(nominatimSearchURL(q, limit, countryCodes), w, r)
 }
 
 // GeoReverse proxifie le géocodage inverse Nominatim.
-// GET /api/geo/reverse?lat=48.85&lon=2.35
+// GET /api/v1/geo/reverse?lat=48.85&lon=2.35
 func GeoReverse(w http.ResponseWriter, r *http.Request) {
 	lat := strings.TrimSpace(r.URL.Query().Get("lat"))
 	lon := strings.TrimSpace(r.URL.Query().Get("lon"))
 	if lat == "" || lon == "" {
-		http.Error(w, "lat et lon requis", http.StatusBadRequest)
+		renderError(w, r, http.StatusBadRequest, "lat et lon requis")
 		return
 	}
 
 	// garde-fou simple
 	if len(lat) > 20 || len(lon) > 20 {
-		http.Error(w, "lat/lon invalides", http.StatusBadRequest)
+		renderError(w, r, http.StatusBadRequest, "lat/lon invalides")
 		return
 	}
 
-	base := "https://nominatim.openstreetmap.org/reverse"
+	nominatimProxy(nominatimReverseURL(lat, lon), w, r)
+}
+
+// nominatimReverseURL construit un géocodage inverse Nominatim (adresse détaillée).
+func nominatimReverseURL(lat, lon string) string {
 	v := url.Values{}
 	v.Set("format", "json")
 	v.Set("lat", lat)
@@ -229,8 +360,7 @@ func GeoReverse(w http.ResponseWriter, r *http.Request) {
 	if em := nominatimEmailParam(); em != "" {
 		v.Set("email", em)
 	}
-
-	nominatimProxy(base+"?"+v.Encode(), w, r)
+	return "https://nominatim.openstreetmap.org/reverse?" + v.Encode()
 }
 
 // (Optionnel) helper si tu veux l'utiliser ailleurs
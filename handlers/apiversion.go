@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ─── Versionnement de l'API JSON ───────────────────────────────────────────
+
+// Les routes JSON sont montées sous /api/v1/ ; /api/* reste un alias
+// (déprécié) le temps que les clients migrent. Changement cassant : /api/v2/.
+const (
+	APIVersion    = "1"
+	APIBase       = "/api/v" + APIVersion
+	legacyAPIBase = "/api"
+)
+
+// HandleAPI enregistre path (ex: "/products") sous APIBase et sous l'alias /api.
+func HandleAPI(mux *http.ServeMux, path string, h http.HandlerFunc) {
+	mux.HandleFunc(APIBase+path, withAPIVersion(h, ""))
+	mux.HandleFunc(legacyAPIBase+path, withAPIVersion(h, APIBase+path))
+}
+
+// withAPIVersion ajoute X-API-Version ; pour un alias, signale la route qui le remplace.
+func withAPIVersion(h http.HandlerFunc, successor string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Version", APIVersion)
+		if successor != "" {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+URLFor(successor)+`>; rel="successor-version"`)
+		}
+		h(w, r)
+	}
+}
+
+// apiRoutePath ramène /api/v1/x à /api/x (comparaisons indépendantes de la version).
+func apiRoutePath(p string) string {
+	if rest, ok := strings.CutPrefix(p, APIBase+"/"); ok {
+		return legacyAPIBase + "/" + rest
+	}
+	return p
+}
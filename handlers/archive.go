@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ─── Archivage ─────────────────────────────────────────────────────────────
+//
+// Une fiche archivée disparaît de la bibliothèque et des stats par défaut, de la
+// carte, de "autour de moi", de la navigation précédent/suivant et du tirage au
+// hasard, mais reste consultable (fiche, collections, comparaison) et cherchable sur /archived.
+
+// ArchiveAfterMonths archive automatiquement les fiches plus anciennes (0 : désactivé).
+var ArchiveAfterMonths int
+
+// Condition SQL des listes par défaut
+const notArchived = "NOT archived"
+
+// Pagination de /archived
+const archivedPerPage = 30
+
+// statsArchiveClause : stats sans les fiches archivées, sauf avec ?archived=1.
+func statsArchiveClause(r *http.Request) string {
+	if parseBoolParam(r.URL.Query().Get("archived")) {
+		return "TRUE"
+	}
+	return notArchived
+}
+
+// ArchiveOldTastings applique la politique ARCHIVE_AFTER_MONTHS (tâche du planificateur).
+// Les fiches désarchivées à la main (archive_exempt) ne sont plus concernées.
+func ArchiveOldTastings(ctx context.Context) (int64, error) {
+	if ArchiveAfterMonths <= 0 {
+		return 0, nil
+	}
+	res, err := execContext(ctx, "archive.auto", `
+		UPDATE tastings SET archived = true
+		WHERE NOT archived AND NOT archive_exempt
+		  AND created_at < now() - make_interval(months => $1)
+	`, ArchiveAfterMonths)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func init() {
+	RegisterCleanup("archive-policy", func(ctx context.Context) {
+		// Mode démo : aucune écriture, même planifiée (ReadOnly est posé après init)
+		if ReadOnly {
+			return
+		}
+		n, err := ArchiveOldTastings(ctx)
+		if err != nil {
+			log.Println("Erreur archivage automatique:", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("🗄️ %d fiche(s) archivée(s) (plus de %d mois)", n, ArchiveAfterMonths)
+		}
+	})
+}
+
+// ArchiveTasting retire une fiche de la bibliothèque.
+// POST /archive  id=...
+func ArchiveTasting(w http.ResponseWriter, r *http.Request) {
+	setArchived(w, r, true)
+}
+
+// UnarchiveTasting remet une fiche dans la bibliothèque (et l'exempte de l'archivage automatique).
+// POST /unarchive  id=...
+func UnarchiveTasting(w http.ResponseWriter, r *http.Request) {
+	setArchived(w, r, false)
+}
+
+func setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	if r.Method != http.MethodPost {
+		renderError(w, r, http.StatusMethodNotAllowed, "Méthode non autorisée.")
+		return
+	}
+	id := strings.TrimSpace(r.FormValue("id"))
+	if id == "" {
+		renderError(w, r, http.StatusBadRequest, "Dégustation manquante.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	// Désarchivage manuel = choix explicite, la politique ne doit pas la réarchiver
+	res, err := DB.ExecContext(ctx, `
+		UPDATE tastings SET archived = $1, archive_exempt = (archive_exempt OR NOT $1)
+		WHERE id = $2
+	`, archived, id)
+	if err != nil {
+		log.Println("Erreur archivage:", err)
+		renderError(w, r, http.StatusInternalServerError, "La fiche n'a pas pu être modifiée, réessaie dans un instant.")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		renderError(w, r, http.StatusNotFound, "Cette dégustation n'existe pas (ou plus).")
+		return
+	}
+
+	publishTastingEvent("tasting.updated", id)
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "archived": archived})
+		return
+	}
+	http.Redirect(w, r, URLFor("/tasting?id="+url.QueryEscape(id)), http.StatusSeeOther)
+}
+
+// ArchivedList affiche les fiches archivées, avec recherche (produit, chocolatier, ville).
+// GET /archived?q=...&page=2
+func ArchivedList(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := parsePositiveInt(r.URL.Query().Get("page"), 1)
+
+	where := "archived"
+	var args []any
+	if q != "" {
+		where += ` AND (f_unaccent(product_name) ILIKE f_unaccent($1) ESCAPE '\'
+			OR f_unaccent(COALESCE(maker,'')) ILIKE f_unaccent($1) ESCAPE '\'
+			OR f_unaccent(COALESCE(city,'')) ILIKE f_unaccent($1) ESCAPE '\')`
+		args = append(args, "%"+escapeLike(q)+"%")
+	}
+
+	var matching int
+	if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tastings WHERE `+where, args...).Scan(&matching); err != nil {
+		log.Println("Erreur compte archives:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
+
+	totalPages := max(1, (matching+archivedPerPage-1)/archivedPerPage)
+	page = min(page, totalPages)
+
+	args = append(args, archivedPerPage, (page-1)*archivedPerPage)
+	tastings, err := queryTastings(ctx, "archived.list", `SELECT`+tastingSelectCols+`FROM tastings
+		WHERE `+where+fmt.Sprintf(`
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		log.Println("Erreur requête archives:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
+
+	pageURL := func(p int) string {
+		v := url.Values{}
+		if q != "" {
+			v.Set("q", q)
+		}
+		if p > 1 {
+			v.Set("page", strconv.Itoa(p))
+		}
+		if len(v) == 0 {
+			return URLFor("/archived")
+		}
+		return URLFor("/archived?" + v.Encode())
+	}
+
+	pager := Pager{Page: page, PerPage: archivedPerPage, TotalPages: totalPages, Total: matching}
+	if page > 1 {
+		pager.PrevURL = pageURL(page - 1)
+	}
+	if page < totalPages {
+		pager.NextURL = pageURL(page + 1)
+	}
+
+	data := struct {
+		Tastings    []Tasting
+		Query       string
+		Pager       Pager
+		AfterMonths int
+	}{
+		Tastings:    tastings,
+		Query:       q,
+		Pager:       pager,
+		AfterMonths: ArchiveAfterMonths,
+	}
+
+	if err := Tmpl.ExecuteTemplate(w, "archived.html", data); err != nil {
+		log.Println("Erreur template archives:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+	}
+}
@@ -0,0 +1,726 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AromaFamily regroupe les arômes d'une même famille (ordre d'affichage conservé).
+type AromaFamily struct {
+	Family string  `json:"family"`
+	Aromas []Aroma `json:"aromas"`
+}
+
+// GroupAromasByFamily regroupe une liste d'arômes déjà triée par famille.
+func GroupAromasByFamily(aromas []Aroma) []AromaFamily {
+	out := make([]AromaFamily, 0)
+	index := map[string]int{}
+	for _, a := range aromas {
+		i, ok := index[a.Family]
+		if !ok {
+			i = len(out)
+			index[a.Family] = i
+			out = append(out, AromaFamily{Family: a.Family})
+		}
+		out[i].Aromas = append(out[i].Aromas, a)
+	}
+	return out
+}
+
+// ─── Recherche arômes (picker) ─────────────────────────────────────────────
+
+// AromaSearch filtre les arômes (en mémoire, sans requête DB) par nom.
+// GET /api/v1/aromas?q=vanille
+func AromaSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if len([]rune(q)) < 2 {
+		writeJSON(w, http.StatusOK, []AromaFamily{})
+		return
+	}
+
+	all, err := GetAromas()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "arômes indisponibles")
+		return
+	}
+
+	var matches []Aroma
+	for _, a := range all {
+		if strings.Contains(strings.ToLower(a.Name), q) {
+			matches = append(matches, a)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, GroupAromasByFamily(matches))
+}
+
+// FamilyCount = une famille d'arômes et son nombre d'arômes.
+type FamilyCount struct {
+	Family string `json:"family"`
+	Count  int    `json:"count"`
+	Color  string `json:"color"`
+}
+
+// AromaFamilyCounts liste les familles dans l'ordre d'affichage du picker
+// (celui de GetAromas), famille vide comptée avec customAromaFamily.
+func AromaFamilyCounts(aromas []Aroma) []FamilyCount {
+	out := make([]FamilyCount, 0)
+	index := map[string]int{}
+	for _, a := range aromas {
+		family := a.Family
+		if family == "" {
+			family = customAromaFamily
+		}
+		i, ok := index[family]
+		if !ok {
+			i = len(out)
+			index[family] = i
+			out = append(out, FamilyCount{Family: family, Color: FamilyColor(family)})
+		}
+		out[i].Count++
+	}
+	return out
+}
+
+// AromaFamilies renvoie les familles d'arômes distinctes (cache, sans requête DB).
+// GET /api/v1/aromas/families
+func AromaFamilies(w http.ResponseWriter, r *http.Request) {
+	all, err := GetAromas()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "arômes indisponibles")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":       true,
+		"total":    len(all),
+		"families": AromaFamilyCounts(all),
+	})
+}
+
+// ─── Résolution nom -> id ──────────────────────────────────────────────────
+
+// Familles des arômes créés automatiquement
+const (
+	importAromaFamily = "Import"
+	customAromaFamily = "Autres" // saisie libre dans les formulaires
+)
+
+// Saisie libre : garde-fous
+const (
+	maxNewAromas       = 10
+	maxAromaNameLength = 60
+)
+
+func aromaKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ResolveAromaIDs fait correspondre des noms d'arômes (insensible à la casse)
+// aux arômes connus, via le cache. Les doublons sont ignorés ; les noms non
+// reconnus sont renvoyés dans unknown (dédoublonnés, casse d'origine).
+func ResolveAromaIDs(names []string) (ids []int, unknown []string) {
+	known := make(map[string]int)
+	aromas, _ := GetAromas()
+	for _, a := range aromas {
+		known[aromaKey(a.Name)] = a.ID
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		key := aromaKey(name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if id, ok := known[key]; ok {
+			ids = append(ids, id)
+		} else {
+			unknown = append(unknown, strings.TrimSpace(name))
+		}
+	}
+	return ids, unknown
+}
+
+// createAromas crée (dans la transaction) les arômes absents de la table, sous `family`.
+// Un arôme existant (même nom, casse différente) est réutilisé plutôt que dupliqué ;
+// créé entre-temps par une autre requête, l'index unique sur lower(name) (migration 011)
+// fait échouer l'insertion sans erreur et l'arôme est relu.
+// Renvoie nom en minuscules -> id. Penser à InvalidateAromaCache après le commit.
+func createAromas(ctx context.Context, tx *sql.Tx, names []string, family string) (map[string]int, error) {
+	out := make(map[string]int, len(names))
+	for _, name := range names {
+		key := aromaKey(name)
+		if key == "" {
+			continue
+		}
+		if _, ok := out[key]; ok {
+			continue
+		}
+
+		// lower() côté Postgres : même clé que l'index unique
+		const lookup = `SELECT id FROM aromas WHERE lower(name) = lower($1)`
+		name = strings.TrimSpace(name)
+		var id int
+		err := tx.QueryRowContext(ctx, lookup, name).Scan(&id)
+		if err == sql.ErrNoRows {
+			err = tx.QueryRowContext(ctx,
+				`INSERT INTO aromas (name, family) VALUES ($1, $2)
+				ON CONFLICT ((lower(name))) DO NOTHING RETURNING id`,
+				name, family,
+			).Scan(&id)
+			if err == sql.ErrNoRows {
+				err = tx.QueryRowContext(ctx, lookup, name).Scan(&id)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[key] = id
+	}
+	return out, nil
+}
+
+// parseNewAromas découpe la saisie libre "new_aromas" ("noisette, tabac; cuir"),
+// noms passés par normalizeText : un arôme collé du web retrouve l'arôme existant.
+func parseNewAromas(s string) []string {
+	var out []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '|' }) {
+		p = sanitizeText(normalizeText(p), maxAromaNameLength)
+		if p != "" && len(out) < maxNewAromas {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// formAromaArray fusionne les arômes cochés (aroma_ids) et la saisie libre (new_aromas)
+// en un tableau Postgres ; les arômes inconnus sont créés dans tx (famille "Autres").
+// created = true si au moins un arôme a été créé (cache à invalider après commit).
+func formAromaArray(ctx context.Context, tx *sql.Tx, r *http.Request) (array string, created bool, err error) {
+	ids := append([]string(nil), r.Form["aroma_ids"]...)
+
+	known, unknown := ResolveAromaIDs(parseNewAromas(r.FormValue("new_aromas")))
+	for _, id := range known {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	if len(unknown) > 0 {
+		newIDs, err := createAromas(ctx, tx, unknown, customAromaFamily)
+		if err != nil {
+			return "", false, err
+		}
+		for _, id := range newIDs {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		created = true
+	}
+
+	// Dédoublonnage (un arôme coché ET retapé)
+	seen := map[string]bool{}
+	uniq := ids[:0]
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if !seen[id] {
+			seen[id] = true
+			uniq = append(uniq, id)
+		}
+	}
+	return buildPgIntArray(uniq), created, nil
+}
+
+// ─── Renommage ─────────────────────────────────────────────────────────────
+
+// UpdateAroma renomme un arôme (et change sa famille si family est fourni).
+// Les fiches référencent l'id : seul le cache est à invalider pour que le
+// nouveau nom apparaisse partout dès la requête suivante.
+// POST /admin/aromas/update  id=12&name=Noisette grillée[&family=Fruits secs]
+func UpdateAroma(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(r.FormValue("id")))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "id invalide")
+		return
+	}
+	name, err := validateField("Nom de l'arôme", r.FormValue("name"), maxAromaNameLength)
+	if err != nil || name == "" {
+		msg := "name requis"
+		if err != nil {
+			msg = err.Error()
+		}
+		writeError(w, http.StatusBadRequest, "bad_request", msg)
+		return
+	}
+	family := normalizeText(r.FormValue("family"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var conflict bool
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		// Un autre arôme porte déjà ce nom : fusion (MergeAromas) plutôt que doublon
+		if err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM aromas WHERE lower(name) = $1 AND id <> $2)`, aromaKey(name), id,
+		).Scan(&conflict); err != nil || conflict {
+			return err
+		}
+		res, err := tx.ExecContext(ctx,
+			`UPDATE aromas SET name = $1, family = COALESCE(NULLIF($2, ''), family) WHERE id = $3`, name, family, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	// Renommage concurrent vers le même nom : refusé par l'index unique (migration 011)
+	if isUniqueViolation(err) {
+		err, conflict = nil, true
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		writeError(w, http.StatusNotFound, "not_found", "arôme introuvable")
+		return
+	case err != nil:
+		log.Println("Erreur renommage arôme:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	case conflict:
+		writeError(w, http.StatusConflict, "conflict", "un autre arôme porte déjà ce nom (fusion : /admin/aromas/merge)")
+		return
+	}
+
+	InvalidateAromaCache()
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "name": name})
+}
+
+// ─── Fusion ────────────────────────────────────────────────────────────────
+
+// errAromaNotFound : from_id ou to_id absent de la table aromas
+var errAromaNotFound = errors.New("arôme introuvable")
+
+// mergeAromas remplace fromID par toID dans les aroma_ids de toutes les fiches
+// (sans doublon, ordre conservé) puis supprime fromID. Renvoie le nom conservé
+// et le nombre de fiches modifiées.
+func mergeAromas(ctx context.Context, fromID, toID int) (name string, tastings int64, err error) {
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		// Verrou des deux arômes : pas de renommage ni de suppression concurrente
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, name FROM aromas WHERE id IN ($1, $2) FOR UPDATE`, fromID, toID)
+		if err != nil {
+			return err
+		}
+		found := 0
+		for rows.Next() {
+			var id int
+			var n string
+			if err := rows.Scan(&id, &n); err != nil {
+				rows.Close()
+				return err
+			}
+			if id == toID {
+				name = n
+			}
+			found++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if found < 2 {
+			return errAromaNotFound
+		}
+
+		// DISTINCT ON garde la première occurrence de chaque id (fiche qui avait les deux)
+		res, err := tx.ExecContext(ctx, `
+			UPDATE tastings t
+			SET aroma_ids = (
+				SELECT COALESCE(array_agg(d.aid ORDER BY d.ord), '{}')
+				FROM (
+					SELECT DISTINCT ON (m.aid) m.aid, m.ord
+					FROM (
+						SELECT CASE WHEN u.aid = $1 THEN $2 ELSE u.aid END AS aid, u.ord
+						FROM unnest(t.aroma_ids) WITH ORDINALITY AS u(aid, ord)
+					) m
+					ORDER BY m.aid, m.ord
+				) d
+			)
+			WHERE $1 = ANY(t.aroma_ids)
+		`, fromID, toID)
+		if err != nil {
+			return err
+		}
+		if tastings, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM aromas WHERE id = $1`, fromID)
+		return err
+	})
+	return name, tastings, err
+}
+
+// MergeAromas fusionne deux arômes (doublons de saisie libre : "vanille" / "Vanille") :
+// les fiches passent de from_id à to_id, puis from_id est supprimé.
+// POST /admin/aromas/merge  from_id=42&to_id=7
+func MergeAromas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	fromID, err1 := strconv.Atoi(strings.TrimSpace(r.FormValue("from_id")))
+	toID, err2 := strconv.Atoi(strings.TrimSpace(r.FormValue("to_id")))
+	if err1 != nil || err2 != nil || fromID <= 0 || toID <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "from_id et to_id invalides")
+		return
+	}
+	if fromID == toID {
+		writeError(w, http.StatusBadRequest, "bad_request", "from_id et to_id identiques")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	name, tastings, err := mergeAromas(ctx, fromID, toID)
+	switch {
+	case errors.Is(err, errAromaNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	case err != nil:
+		log.Println("Erreur fusion arômes:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	InvalidateAromaCache()
+	log.Printf("Arôme %d fusionné dans %d (%q) : %d dégustations modifiées", fromID, toID, name, tastings)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok": true, "from_id": fromID, "to_id": toID, "name": name, "tastings": tastings,
+	})
+}
+
+// ─── Familles (renommage, réaffectation) ───────────────────────────────────
+
+// Longueur max d'un nom de famille
+const maxAromaFamilyLength = 60
+
+// Nombre max d'arômes réaffectés par requête
+const maxFamilyAssignIDs = 500
+
+// RenameAromaFamily renomme une famille : tous ses arômes passent dans la nouvelle
+// (qui peut déjà exister : les deux familles sont alors fusionnées).
+// POST /admin/aromas/families/rename  from=Fruité&to=Fruits rouges
+func RenameAromaFamily(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	from := strings.TrimSpace(r.FormValue("from"))
+	to, err := validateField("Famille", r.FormValue("to"), maxAromaFamilyLength)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "from et to requis")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var updated int64
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE aromas SET family = $1 WHERE family = $2`, to, from)
+		if err != nil {
+			return err
+		}
+		updated, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.Println("Erreur renommage famille:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	if updated == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "famille introuvable")
+		return
+	}
+
+	InvalidateAromaCache()
+	log.Printf("Famille %q renommée en %q (%d arômes)", from, to, updated)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "from": from, "to": to, "updated": updated})
+}
+
+// AssignAromaFamily déplace des arômes dans une famille (existante ou nouvelle).
+// POST /admin/aromas/families/assign  ids=3,8,12&family=Agrumes
+func AssignAromaFamily(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	family, err := validateField("Famille", r.FormValue("family"), maxAromaFamilyLength)
+	if err != nil || family == "" {
+		msg := "family requis"
+		if err != nil {
+			msg = err.Error()
+		}
+		writeError(w, http.StatusBadRequest, "bad_request", msg)
+		return
+	}
+
+	// ids=1,2,3 ou ids=1&ids=2
+	var ids []int64
+	for _, s := range parseIDList(strings.Join(r.Form["ids"], ",")) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "id invalide : "+s)
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "ids requis")
+		return
+	}
+	if len(ids) > maxFamilyAssignIDs {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("trop d'arômes (max %d)", maxFamilyAssignIDs))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var updated int64
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE aromas SET family = $1 WHERE id = ANY($2)`, family, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		updated, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.Println("Erreur réaffectation famille:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	if updated == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "aucun arôme trouvé")
+		return
+	}
+
+	InvalidateAromaCache()
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "family": family, "updated": updated})
+}
+
+// ─── Roue des arômes (sunburst) ────────────────────────────────────────────
+
+type wheelAroma struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type wheelFamily struct {
+	Family string       `json:"family"`
+	Color  string       `json:"color"` // FamilyColor
+	Count  int          `json:"count"` // somme des arômes de la famille
+	Aromas []wheelAroma `json:"aromas"`
+}
+
+// aromaUsageCounts compte les mentions de chaque arôme dans les dégustations
+// retenues par where (statsArchiveClause).
+func aromaUsageCounts(ctx context.Context, where string) (map[int]int, error) {
+	rows, err := queryContext(ctx, "aromas.usage", `
+		SELECT aid, COUNT(*)
+		FROM tastings t
+		CROSS JOIN LATERAL unnest(t.aroma_ids) AS aid
+		WHERE `+where+`
+		GROUP BY aid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var id, n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
+
+// AromaWheel renvoie familles -> arômes -> nombre d'utilisations (format sunburst).
+// Les arômes jamais utilisés sont omis, sauf avec all=1 ; fiches archivées
+// comptées seulement avec archived=1 (comme les stats).
+// GET /api/v1/aromas/wheel?all=1[&archived=1]
+func AromaWheel(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	counts, err := aromaUsageCounts(ctx, statsArchiveClause(r))
+	if err != nil {
+		log.Println("Erreur roue arômes:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	aromas, err := GetAromas()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "arômes indisponibles")
+		return
+	}
+	all := parseBoolParam(r.URL.Query().Get("all"))
+
+	families := make([]wheelFamily, 0)
+	total := 0
+	for _, g := range GroupAromasByFamily(aromas) {
+		f := wheelFamily{Family: g.Family, Aromas: make([]wheelAroma, 0, len(g.Aromas))}
+		if f.Family == "" {
+			f.Family = customAromaFamily
+		}
+		f.Color = FamilyColor(f.Family)
+		for _, a := range g.Aromas {
+			n := counts[a.ID]
+			if n == 0 && !all {
+				continue
+			}
+			f.Aromas = append(f.Aromas, wheelAroma{ID: a.ID, Name: a.Name, Count: n})
+			f.Count += n
+		}
+		if len(f.Aromas) == 0 {
+			continue
+		}
+		total += f.Count
+		families = append(families, f)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":       true,
+		"total":    total,
+		"families": families,
+	})
+}
+
+// ─── Palette des familles ──────────────────────────────────────────────────
+
+// Saturation / luminosité communes : seules les teintes varient, toutes lisibles sur fond crème
+const (
+	familySaturation = 0.45
+	familyLightness  = 0.42
+)
+
+// FamilyColor renvoie la couleur ("#rrggbb") d'une famille d'arômes. La teinte est
+// tirée d'un hash du nom : stable d'un chargement, d'une session et d'un serveur à l'autre.
+func FamilyColor(family string) string {
+	key := aromaKey(family)
+	if key == "" {
+		key = aromaKey(customAromaFamily)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return hslToHex(float64(h.Sum32()%360), familySaturation, familyLightness)
+}
+
+// hslToHex convertit h (0..360), s et l (0..1) en "#rrggbb".
+func hslToHex(h, s, l float64) string {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	to8 := func(v float64) uint8 { return uint8(math.Round((v + m) * 255)) }
+	return fmt.Sprintf("#%02x%02x%02x", to8(r), to8(g), to8(b))
+}
+
+// AromaPalette renvoie la couleur de chaque famille et celle de chaque arôme
+// (celle de sa famille), pour colorer les puces côté client.
+// GET /api/v1/aromas/palette
+func AromaPalette(w http.ResponseWriter, r *http.Request) {
+	all, err := GetAromas()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "arômes indisponibles")
+		return
+	}
+
+	families := map[string]string{}
+	aromas := map[string]string{}
+	for _, a := range all {
+		family := a.Family
+		if family == "" {
+			family = customAromaFamily
+		}
+		color, ok := families[family]
+		if !ok {
+			color = FamilyColor(family)
+			families[family] = color
+		}
+		aromas[a.Name] = color
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":       true,
+		"families": families,
+		"aromas":   aromas,
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tastingRow simule une ligne tastingSelectCols ; seuls les arômes sont renseignés.
+type tastingRow struct{ aromaIDs string }
+
+func (r tastingRow) Scan(dest ...any) error {
+	// Colonnes lues dans tastingSelectCols : le test suit l'ordre réel de la requête
+	cols := strings.Split(strings.TrimSpace(tastingSelectCols), ",\n")
+	if len(dest) != len(cols) {
+		return fmt.Errorf("%d destinations pour %d colonnes", len(dest), len(cols))
+	}
+	i := slices.IndexFunc(cols, func(c string) bool { return strings.Contains(c, "aroma_ids") })
+	if i < 0 {
+		return errors.New("colonne aroma_ids absente de tastingSelectCols")
+	}
+	p, ok := dest[i].(*string)
+	if !ok {
+		return fmt.Errorf("aroma_ids scannée dans %T", dest[i])
+	}
+	*p = r.aromaIDs
+	return nil
+}
+
+func TestAromaRenameAfterInvalidation(t *testing.T) {
+	saved := aromaLoader
+	t.Cleanup(func() {
+		aromaLoader = saved
+		InvalidateAromaCache()
+	})
+
+	name := "Noisette"
+	loads := 0
+	aromaLoader = func() ([]Aroma, error) {
+		loads++
+		return []Aroma{{ID: 1, Name: name, Family: "Fruits secs"}, {ID: 2, Name: "Tabac", Family: "Épices"}}, nil
+	}
+	InvalidateAromaCache()
+
+	names := func() []string {
+		tasting, err := scanTasting(tastingRow{aromaIDs: "{1,2}"}, aromaNameMap())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tasting.AromaNames
+	}
+
+	if got := names(); !slices.Equal(got, []string{"Noisette", "Tabac"}) {
+		t.Fatalf("avant renommage : %q", got)
+	}
+
+	// Renommé en base : le cache sert encore l'ancien nom tant qu'il n'est pas invalidé
+	name = "Noisette grillée"
+	if got := names(); got[0] != "Noisette" || loads != 1 {
+		t.Fatalf("cache ignoré : %q (%d chargements)", got, loads)
+	}
+
+	InvalidateAromaCache()
+	if got := names(); !slices.Equal(got, []string{"Noisette grillée", "Tabac"}) {
+		t.Errorf("après invalidation : %q, want nouveau nom", got)
+	}
+	if loads != 2 {
+		t.Errorf("%d chargements, want 2", loads)
+	}
+}
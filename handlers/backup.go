@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ─── Sauvegarde JSON (export / import) ─────────────────────────────────────
+
+// Version du format de sauvegarde (à incrémenter si la structure change)
+const backupVersion = 1
+
+type Backup struct {
+	Version     int                `json:"version"`
+	ExportedAt  time.Time          `json:"exported_at"`
+	Aromas      []Aroma            `json:"aromas"`
+	Tastings    []Tasting          `json:"tastings"`
+	Collections []backupCollection `json:"collections"`
+}
+
+type backupCollection struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Emoji      string   `json:"emoji"`
+	TastingIDs []string `json:"tasting_ids"`
+}
+
+// BackupStats résume un export ou un import.
+type BackupStats struct {
+	Aromas      int
+	Tastings    int
+	Collections int
+	Skipped     int // dégustations déjà présentes (import)
+}
+
+// ExportBackup écrit toute la base (arômes, dégustations, collections) en JSON.
+func ExportBackup(ctx context.Context, w io.Writer) (BackupStats, error) {
+	var st BackupStats
+	b := Backup{Version: backupVersion, ExportedAt: time.Now().UTC()}
+
+	rows, err := DB.QueryContext(ctx, `SELECT id, name, COALESCE(family,'') FROM aromas ORDER BY id`)
+	if err != nil {
+		return st, fmt.Errorf("arômes: %w", err)
+	}
+	for rows.Next() {
+		var a Aroma
+		if err := rows.Scan(&a.ID, &a.Name, &a.Family); err != nil {
+			rows.Close()
+			return st, fmt.Errorf("scan arôme: %w", err)
+		}
+		b.Aromas = append(b.Aromas, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return st, fmt.Errorf("arômes: %w", err)
+	}
+
+	if b.Tastings, err = queryTastings(ctx, "backup.tastings", `SELECT`+tastingSelectCols+`FROM tastings ORDER BY created_at`); err != nil {
+		return st, fmt.Errorf("dégustations: %w", err)
+	}
+
+	if b.Collections, err = exportCollections(ctx); err != nil {
+		return st, fmt.Errorf("collections: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return st, err
+	}
+
+	st.Aromas, st.Tastings, st.Collections = len(b.Aromas), len(b.Tastings), len(b.Collections)
+	return st, nil
+}
+
+func exportCollections(ctx context.Context) ([]backupCollection, error) {
+	rows, err := DB.QueryContext(ctx, `
+		SELECT c.id, c.name, COALESCE(c.emoji,''), ct.tasting_id
+		FROM collections c
+		LEFT JOIN collection_tastings ct ON ct.collection_id = c.id
+		ORDER BY c.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []backupCollection
+	for rows.Next() {
+		var c backupCollection
+		var tastingID sql.NullString
+		if err := rows.Scan(&c.ID, &c.Name, &c.Emoji, &tastingID); err != nil {
+			return nil, err
+		}
+		if n := len(out); n == 0 || out[n-1].ID != c.ID {
+			out = append(out, c)
+		}
+		if tastingID.Valid {
+			last := &out[len(out)-1]
+			last.TastingIDs = append(last.TastingIDs, tastingID.String)
+		}
+	}
+	return out, rows.Err()
+}
+
+// ImportBackup restaure une sauvegarde ExportBackup, en une seule transaction.
+// Les ids sont remappés : arômes et collections retrouvés par nom, dégustations
+// par (product_name, created_at). Réimporter le même fichier ne duplique rien.
+func ImportBackup(ctx context.Context, r io.Reader) (BackupStats, error) {
+	return importBackup(ctx, r, false)
+}
+
+// PreviewBackup déroule l'import complet puis annule la transaction : mêmes
+// contrôles et mêmes chiffres qu'ImportBackup, sans rien écrire.
+func PreviewBackup(ctx context.Context, r io.Reader) (BackupStats, error) {
+	return importBackup(ctx, r, true)
+}
+
+// errBackupDryRun annule la transaction d'un aperçu une fois tout vérifié.
+var errBackupDryRun = errors.New("aperçu : transaction annulée")
+
+func importBackup(ctx context.Context, r io.Reader, dryRun bool) (BackupStats, error) {
+	var st BackupStats
+
+	var b Backup
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return st, fmt.Errorf("sauvegarde illisible: %w", err)
+	}
+	if b.Version != backupVersion {
+		return st, fmt.Errorf("version de sauvegarde non supportée: %d", b.Version)
+	}
+
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		// 1) Arômes : réutilisés par nom, créés sinon (dans leur famille d'origine)
+		byFamily := map[string][]string{}
+		for _, a := range b.Aromas {
+			byFamily[a.Family] = append(byFamily[a.Family], a.Name)
+		}
+		aromaIDs := map[string]int{}
+		for family, names := range byFamily {
+			ids, err := createAromas(ctx, tx, names, family)
+			if err != nil {
+				return fmt.Errorf("arômes: %w", err)
+			}
+			for k, id := range ids {
+				aromaIDs[k] = id
+			}
+		}
+		oldAroma := map[int]int{}
+		for _, a := range b.Aromas {
+			if id, ok := aromaIDs[aromaKey(a.Name)]; ok {
+				oldAroma[a.ID] = id
+			}
+		}
+
+		// 2) Dégustations
+		tastingIDs := map[string]string{}
+		for _, t := range b.Tastings {
+			var id string
+			err := tx.QueryRowContext(ctx,
+				`SELECT id FROM tastings WHERE product_name = $1 AND created_at = $2 LIMIT 1`,
+				t.ProductName, t.CreatedAt,
+			).Scan(&id)
+			if err == nil {
+				tastingIDs[t.ID] = id
+				st.Skipped++
+				continue
+			}
+			if err != sql.ErrNoRows {
+				return fmt.Errorf("dégustation %s: %w", t.ID, err)
+			}
+
+			ids := make([]string, 0, len(t.AromaIDs))
+			for _, old := range t.AromaIDs {
+				if nid, ok := oldAroma[old]; ok {
+					ids = append(ids, strconv.Itoa(nid))
+				}
+			}
+
+			variants, err := json.Marshal(t.PhotoVariants)
+			if err != nil {
+				return err
+			}
+			// Pas de file de traitement à reprendre : la photo sauvegardée est en ligne ou absente
+			photoStatus := ""
+			if t.PhotoURL != "" {
+				photoStatus = PhotoDone
+			}
+
+			err = tx.QueryRowContext(ctx, `
+				INSERT INTO tastings (
+					product_name, maker, city, score, notes, mode,
+					aroma_ids, latitude, longitude,
+					vue_quality, snap_quality, melt_quality, finish_length,
+					photo_url, blur_hash, photo_color, photo_variants, photo_status, archived, created_at
+				)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)
+				RETURNING id
+			`,
+				t.ProductName, t.Maker, t.City, t.Score, t.Notes, validateMode(t.Mode),
+				buildPgIntArray(ids), t.Latitude, t.Longitude,
+				t.VueQuality, t.SnapQuality, t.MeltQuality, t.FinishLength,
+				t.PhotoURL, t.BlurHash, t.PhotoColor, string(variants), photoStatus, t.Archived, t.CreatedAt,
+			).Scan(&id)
+			if err != nil {
+				return fmt.Errorf("dégustation %s: %w", t.ID, err)
+			}
+			tastingIDs[t.ID] = id
+			st.Tastings++
+		}
+
+		// 3) Collections (par nom) + liaisons
+		for _, c := range b.Collections {
+			var id string
+			err := tx.QueryRowContext(ctx, `SELECT id FROM collections WHERE name = $1 LIMIT 1`, c.Name).Scan(&id)
+			if err == sql.ErrNoRows {
+				err = tx.QueryRowContext(ctx,
+					`INSERT INTO collections (name, emoji) VALUES ($1, $2) RETURNING id`, c.Name, c.Emoji,
+				).Scan(&id)
+				st.Collections++
+			}
+			if err != nil {
+				return fmt.Errorf("collection %q: %w", c.Name, err)
+			}
+
+			for _, old := range c.TastingIDs {
+				tid, ok := tastingIDs[old]
+				if !ok {
+					continue
+				}
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO collection_tastings (collection_id, tasting_id)
+					VALUES ($1, $2)
+					ON CONFLICT DO NOTHING
+				`, id, tid); err != nil {
+					return fmt.Errorf("collection %q: %w", c.Name, err)
+				}
+			}
+		}
+		if dryRun {
+			return errBackupDryRun
+		}
+		return nil
+	})
+	if dryRun && errors.Is(err, errBackupDryRun) {
+		st.Aromas = len(b.Aromas)
+		return st, nil
+	}
+	if err != nil {
+		return BackupStats{}, err
+	}
+
+	st.Aromas = len(b.Aromas)
+	InvalidateAromaCache()
+	InvalidateCollectionsCache()
+	return st, nil
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ─── Code-barres (Open Food Facts) ─────────────────────────────────────────
+
+// BarcodeLookup active GET /api/v1/product/barcode (BARCODE_LOOKUP=1).
+var BarcodeLookup bool
+
+const (
+	openFoodFactsURL    = "https://world.openfoodfacts.org/api/v2/product/"
+	barcodeFoundTTL     = 7 * 24 * time.Hour // fiches produit très stables
+	barcodeNotFoundTTL  = time.Hour          // le produit peut être ajouté entre-temps
+	minBarcodeLength    = 8                  // EAN-8
+	maxBarcodeLength    = 14                 // GTIN-14
+	barcodeNotFoundBody = `{"ok":false,"error":{"code":"not_found","message":"produit introuvable"}}`
+)
+
+// Réponses normalisées (JSON prêt à renvoyer), clé = code-barres
+var barcodeCache = &geoCache{entries: make(map[string]geoCacheEntry)}
+
+// BarcodeProduct = champs utiles pour préremplir le formulaire d'ajout.
+type BarcodeProduct struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Maker    string `json:"maker"`
+	ImageURL string `json:"image_url"`
+}
+
+// Sous-ensemble de la réponse Open Food Facts (fields=…)
+type offResponse struct {
+	Status  int `json:"status"` // 1 = trouvé
+	Product struct {
+		ProductName   string `json:"product_name"`
+		ProductNameFR string `json:"product_name_fr"`
+		Brands        string `json:"brands"`
+		ImageFrontURL string `json:"image_front_url"`
+		ImageURL      string `json:"image_url"`
+	} `json:"product"`
+}
+
+// openFoodFactsUserAgent : OFF demande "App/Version (contact)" ; à défaut, celui de Nominatim.
+func openFoodFactsUserAgent() string {
+	if ua := strings.TrimSpace(os.Getenv("OFF_USER_AGENT")); ua != "" {
+		return ua
+	}
+	return nominatimUserAgent()
+}
+
+// validBarcode : EAN/UPC/GTIN, chiffres uniquement.
+func validBarcode(code string) bool {
+	if len(code) < minBarcodeLength || len(code) > maxBarcodeLength {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ProductByBarcode cherche un produit sur Open Food Facts (côté serveur, avec cache).
+// GET /api/v1/product/barcode?code=3017620422003
+func ProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	if !BarcodeLookup {
+		writeError(w, http.StatusNotFound, "not_found", "recherche par code-barres désactivée")
+		return
+	}
+
+	code := strings.TrimSpace(r.URL.Query().Get("code"))
+	if !validBarcode(code) {
+		writeError(w, http.StatusBadRequest, "bad_request", "code-barres invalide (8 à 14 chiffres)")
+		return
+	}
+
+	if body, ok := barcodeCache.get(code); ok {
+		writeBarcodeBody(w, body)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet,
+		openFoodFactsURL+code+".json?fields=product_name,product_name_fr,brands,image_front_url,image_url", nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	req.Header.Set("User-Agent", openFoodFactsUserAgent())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := geoHTTPClient.Do(req)
+	if err != nil {
+		log.Println("Erreur Open Food Facts:", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "Open Food Facts indisponible")
+		return
+	}
+	defer resp.Body.Close()
+
+	var off offResponse
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// Produit inconnu : 404 côté OFF (ou status 0 selon les versions de l'API)
+	case resp.StatusCode != http.StatusOK:
+		log.Println("Erreur Open Food Facts: statut", resp.Status)
+		writeError(w, http.StatusBadGateway, "upstream_error", "Open Food Facts indisponible")
+		return
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(&off); err != nil {
+			log.Println("Erreur décodage Open Food Facts:", err)
+			writeError(w, http.StatusBadGateway, "upstream_error", "réponse Open Food Facts illisible")
+			return
+		}
+	}
+
+	p := normalizeOFFProduct(code, off)
+	if p == nil {
+		barcodeCache.set(code, []byte(barcodeNotFoundBody), barcodeNotFoundTTL)
+		writeBarcodeBody(w, []byte(barcodeNotFoundBody))
+		return
+	}
+
+	body, err := json.Marshal(struct {
+		OK bool `json:"ok"`
+		*BarcodeProduct
+	}{true, p})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	barcodeCache.set(code, body, barcodeFoundTTL)
+	writeBarcodeBody(w, body)
+}
+
+// normalizeOFFProduct garde le nom (français de préférence) et la première marque.
+// nil si le produit est inconnu ou sans nom.
+func normalizeOFFProduct(code string, off offResponse) *BarcodeProduct {
+	if off.Status != 1 {
+		return nil
+	}
+	name := normalizeText(off.Product.ProductNameFR)
+	if name == "" {
+		name = normalizeText(off.Product.ProductName)
+	}
+	if name == "" {
+		return nil
+	}
+
+	maker, _, _ := strings.Cut(off.Product.Brands, ",")
+	image := off.Product.ImageFrontURL
+	if image == "" {
+		image = off.Product.ImageURL
+	}
+
+	return &BarcodeProduct{
+		Code:     code,
+		Name:     truncateRunes(name, MaxProductNameLength),
+		Maker:    truncateRunes(normalizeText(maker), MaxMakerLength),
+		ImageURL: image,
+	}
+}
+
+// writeBarcodeBody renvoie une réponse mise en cache (introuvable => 404).
+func writeBarcodeBody(w http.ResponseWriter, body []byte) {
+	status := http.StatusOK
+	if string(body) == barcodeNotFoundBody {
+		status = http.StatusNotFound
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// truncateRunes coupe s à max caractères (noms OFF parfois très longs).
+func truncateRunes(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return strings.TrimSpace(string(r[:max]))
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ─── Index chocolatiers / villes ───────────────────────────────────────────
+
+const facetPerPage = 50
+
+// facetIndex décrit une page d'index (colonne en liste blanche, jamais venant du client).
+type facetIndex struct {
+	column   string
+	path     string
+	template string
+}
+
+var (
+	makersIndex = facetIndex{column: "maker", path: "/makers", template: "makers.html"}
+	citiesIndex = facetIndex{column: "city", path: "/cities", template: "cities.html"}
+)
+
+// FacetEntry = une valeur distincte (chocolatier ou ville) et ses dégustations.
+type FacetEntry struct {
+	Value    string
+	Count    int
+	AvgScore float64 // 0 : aucune fiche notée
+	Variants bool    // même valeur à la casse/aux accents près : à fusionner
+	URL      string  // bibliothèque filtrée sur cette valeur
+}
+
+// MakersIndex liste les chocolatiers (nombre de fiches, note moyenne).
+// GET /makers?q=...&page=2
+func MakersIndex(w http.ResponseWriter, r *http.Request) { renderFacetIndex(w, r, makersIndex) }
+
+// CitiesIndex liste les villes (nombre de fiches, note moyenne).
+// GET /cities?q=...&page=2
+func CitiesIndex(w http.ResponseWriter, r *http.Request) { renderFacetIndex(w, r, citiesIndex) }
+
+func renderFacetIndex(w http.ResponseWriter, r *http.Request, f facetIndex) {
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := parsePositiveInt(r.URL.Query().Get("page"), 1)
+
+	col := f.column
+	where := notArchived + ` AND COALESCE(` + col + `,'') <> ''`
+	var args []any
+	if q != "" {
+		where += ` AND f_unaccent(` + col + `) ILIKE f_unaccent($1) ESCAPE '\'`
+		args = append(args, "%"+escapeLike(q)+"%")
+	}
+
+	var matching int
+	if err := DB.QueryRowContext(ctx, `SELECT COUNT(DISTINCT `+col+`) FROM tastings WHERE `+where, args...).Scan(&matching); err != nil {
+		log.Println("Erreur compte index "+col+":", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
+
+	totalPages := max(1, (matching+facetPerPage-1)/facetPerPage)
+	page = min(page, totalPages)
+
+	// La fenêtre est évaluée après le GROUP BY et avant LIMIT : elle voit toutes les valeurs
+	args = append(args, facetPerPage, (page-1)*facetPerPage)
+	rows, err := DB.QueryContext(ctx, `
+		SELECT `+col+`, COUNT(*), COALESCE(AVG(score) FILTER (WHERE score > 0), 0),
+			COUNT(*) OVER (PARTITION BY lower(f_unaccent(trim(`+col+`)))) > 1
+		FROM tastings
+		WHERE `+where+`
+		GROUP BY `+col+fmt.Sprintf(`
+		ORDER BY COUNT(*) DESC, `+col+`
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		log.Println("Erreur index "+col+":", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
+	defer rows.Close()
+
+	entries := make([]FacetEntry, 0)
+	for rows.Next() {
+		var e FacetEntry
+		var avg sql.NullFloat64
+		if err := rows.Scan(&e.Value, &e.Count, &avg, &e.Variants); err != nil {
+			log.Println("Erreur scan index "+col+":", err)
+			continue
+		}
+		e.AvgScore = avg.Float64
+		e.URL = URLFor("/?q=" + url.QueryEscape(e.Value))
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur rows index "+col+":", err)
+	}
+
+	pageURL := func(p int) string {
+		v := url.Values{}
+		if q != "" {
+			v.Set("q", q)
+		}
+		if p > 1 {
+			v.Set("page", strconv.Itoa(p))
+		}
+		if len(v) == 0 {
+			return URLFor(f.path)
+		}
+		return URLFor(f.path + "?" + v.Encode())
+	}
+
+	pager := Pager{Page: page, PerPage: facetPerPage, TotalPages: totalPages, Total: matching}
+	if page > 1 {
+		pager.PrevURL = pageURL(page - 1)
+	}
+	if page < totalPages {
+		pager.NextURL = pageURL(page + 1)
+	}
+
+	data := struct {
+		Entries []FacetEntry
+		Query   string
+		Pager   Pager
+	}{
+		Entries: entries,
+		Query:   q,
+		Pager:   pager,
+	}
+
+	if err := Tmpl.ExecuteTemplate(w, f.template, data); err != nil {
+		log.Println("Erreur template "+f.template+":", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+	}
+}
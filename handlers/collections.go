@@ -5,22 +5,40 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Collection struct {
-	ID    string
-	Name  string
-	Emoji string
-	Count int
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Emoji string `json:"emoji"`
+	Count int    `json:"count,omitempty"`
 }
 
 // timeout DB par défaut (aligné avec tastings.go)
 const collectionsDBTimeout = 5 * time.Second
 
+// Pagination de la page collection
+const (
+	defaultCollectionPerPage = 24
+	maxCollectionPerPage     = 100
+)
+
+// Pager = infos de pagination passées aux templates
+type Pager struct {
+	Page       int
+	PerPage    int
+	TotalPages int
+	Total      int // nombre de résultats (après recherche)
+	PrevURL    string
+	NextURL    string
+}
+
 // ListCollections affiche la page principale listant toutes les collections
 func ListCollections(w http.ResponseWriter, r *http.Request) {
 	collections := GetCollections()
@@ -33,15 +51,55 @@ func ListCollections(w http.ResponseWriter, r *http.Request) {
 
 	if err := Tmpl.ExecuteTemplate(w, "collections_list.html", data); err != nil {
 		log.Println("Erreur template collections_list:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 	}
 }
 
+// Cache de la liste des collections (avec compteurs) : la jointure GROUP BY
+// tournait à chaque affichage de l'accueil. Invalidé à chaque changement d'appartenance.
+const collectionsCacheTTL = 30 * time.Second
+
+var collectionsCache struct {
+	mu          sync.RWMutex
+	collections []Collection
+	expiresAt   time.Time
+}
+
+// InvalidateCollectionsCache force le recalcul des collections à la prochaine lecture.
+func InvalidateCollectionsCache() {
+	collectionsCache.mu.Lock()
+	collectionsCache.collections = nil
+	collectionsCache.expiresAt = time.Time{}
+	collectionsCache.mu.Unlock()
+}
+
+// GetCollections renvoie les collections (plus récentes d'abord) et leur nombre de fiches, via le cache.
 func GetCollections() []Collection {
+	collectionsCache.mu.RLock()
+	if collectionsCache.collections != nil && time.Now().Before(collectionsCache.expiresAt) {
+		cols := collectionsCache.collections
+		collectionsCache.mu.RUnlock()
+		return cols
+	}
+	collectionsCache.mu.RUnlock()
+
+	cols, err := loadCollections()
+	if err != nil {
+		log.Println("Erreur collections:", err)
+		return nil
+	}
+	collectionsCache.mu.Lock()
+	collectionsCache.collections = cols
+	collectionsCache.expiresAt = time.Now().Add(collectionsCacheTTL)
+	collectionsCache.mu.Unlock()
+	return cols
+}
+
+func loadCollections() ([]Collection, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), collectionsDBTimeout)
 	defer cancel()
 
-	rows, err := DB.QueryContext(ctx, `
+	rows, err := queryContext(ctx, "collections.list", `
 		SELECT c.id, c.name, c.emoji, COUNT(ct.tasting_id)
 		FROM collections c
 		LEFT JOIN collection_tastings ct ON ct.collection_id = c.id
@@ -49,12 +107,11 @@ func GetCollections() []Collection {
 		ORDER BY c.created_at DESC
 	`)
 	if err != nil {
-		log.Println("Erreur collections:", err)
-		return nil
+		return nil, err
 	}
 	defer rows.Close()
 
-	var cols []Collection
+	cols := make([]Collection, 0)
 	for rows.Next() {
 		var c Collection
 		if err := rows.Scan(&c.ID, &c.Name, &c.Emoji, &c.Count); err != nil {
@@ -63,18 +120,14 @@ func GetCollections() []Collection {
 		}
 		cols = append(cols, c)
 	}
-	if err := rows.Err(); err != nil {
-		log.Println("Erreur rows collections:", err)
-	}
-
-	return cols
+	return cols, rows.Err()
 }
 
 // ViewCollection affiche la page d'une collection avec ses dégustations
 func ViewCollection(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimSpace(r.URL.Query().Get("id"))
 	if id == "" {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
@@ -86,136 +139,124 @@ func ViewCollection(w http.ResponseWriter, r *http.Request) {
 		Scan(&coll.ID, &coll.Name, &coll.Emoji)
 	if err != nil {
 		log.Println("Collection introuvable:", err)
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
-	rows, err := DB.QueryContext(ctx, `
-		SELECT
-			t.id,
-			t.product_name,
-			COALESCE(t.maker,''),
-			COALESCE(t.city,''),
-			COALESCE(t.score,0),
-			COALESCE(t.mode,'quick'),
-			COALESCE(t.notes,''),
-			COALESCE(t.photo_url,''),
-			t.latitude,
-			t.longitude,
-			t.created_at,
-			COALESCE(t.aroma_ids::text,'{}')
-		FROM tastings t
-		JOIN collection_tastings ct ON ct.tasting_id = t.id
-		WHERE ct.collection_id = $1
-		ORDER BY t.created_at DESC
-	`, id)
-	if err != nil {
-		log.Println("Erreur requête collection tastings:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+	// Stats sur toute la collection (pas seulement la page courante)
+	const inCollection = `id IN (SELECT tasting_id FROM collection_tastings WHERE collection_id = $1)`
+
+	var total int
+	if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tastings WHERE `+inCollection, id).Scan(&total); err != nil {
+		log.Println("Erreur stats collection:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
-	defer rows.Close()
-
-	allAromas := GetAromas()
-	aMap := aromaMapFromSlice(allAromas)
-
-	var tastings []Tasting
-	var totalScore float64
-	var scoredCount int // compter seulement les fiches avec une note
-	cityCount := map[string]int{}
-
-	for rows.Next() {
-		var t Tasting
-		var aromaIDsRaw string
-		var lat, lng sql.NullFloat64
-
-		if err := rows.Scan(
-			&t.ID, &t.ProductName, &t.Maker, &t.City,
-			&t.Score, &t.Mode, &t.Notes, &t.PhotoURL,
-			&lat, &lng, &t.CreatedAt, &aromaIDsRaw,
-		); err != nil {
-			log.Println("Erreur scan:", err)
-			continue
-		}
-
-		if lat.Valid {
-			v := lat.Float64
-			t.Latitude = &v
-		}
-		if lng.Valid {
-			v := lng.Float64
-			t.Longitude = &v
-		}
 
-		t.AromaIDs = parsePgIntArray(aromaIDsRaw)
-		for _, aid := range t.AromaIDs {
-			if name, ok := aMap[aid]; ok {
-				t.AromaNames = append(t.AromaNames, name)
-			}
-		}
+	// moyennes calculées uniquement sur les fiches notées
+	avgs, err := scoreAverages(ctx, inCollection, id)
+	if err != nil {
+		log.Println("Erreur moyenne collection:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
 
-		if t.Score > 0 {
-			totalScore += t.Score
-			scoredCount++
-		}
-		if t.City != "" {
-			cityCount[t.City]++
+	var topCity string
+	err = DB.QueryRowContext(ctx, `SELECT city FROM tastings
+		WHERE `+inCollection+` AND COALESCE(city,'') <> ''
+		GROUP BY city ORDER BY COUNT(*) DESC, city LIMIT 1`, id).Scan(&topCity)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("Erreur ville collection:", err)
+	}
+
+	// Recherche (nom de produit) + pagination
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := parsePositiveInt(r.URL.Query().Get("page"), 1)
+	perPage := min(parsePositiveInt(r.URL.Query().Get("per_page"), defaultCollectionPerPage), maxCollectionPerPage)
+
+	where := inCollection
+	args := []any{id}
+	matching := total
+	if q != "" {
+		where += ` AND f_unaccent(product_name) ILIKE f_unaccent($2) ESCAPE '\'`
+		args = append(args, "%"+escapeLike(q)+"%")
+		if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tastings WHERE `+where, args...).Scan(&matching); err != nil {
+			log.Println("Erreur recherche collection:", err)
+			renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+			return
 		}
-
-		tastings = append(tastings, t)
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Println("Erreur rows collection tastings:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+	totalPages := max(1, (matching+perPage-1)/perPage)
+	page = min(page, totalPages)
+
+	args = append(args, perPage, (page-1)*perPage)
+	tastings, err := queryTastings(ctx, "collection.tastings", `SELECT`+tastingSelectCols+`FROM tastings
+		WHERE `+where+fmt.Sprintf(`
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		log.Println("Erreur requête collection tastings:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
 
-	// moyenne calculée uniquement sur les fiches notées
-	avgScore := ""
-	if scoredCount > 0 {
-		avg := math.Round((totalScore/float64(scoredCount))*10) / 10
-		s := fmt.Sprintf("%.1f", avg)
-		if strings.HasSuffix(s, ".0") {
-			s = s[:len(s)-2]
+	pageURL := func(p int) string {
+		v := url.Values{}
+		v.Set("id", id)
+		if q != "" {
+			v.Set("q", q)
+		}
+		if perPage != defaultCollectionPerPage {
+			v.Set("per_page", strconv.Itoa(perPage))
 		}
-		avgScore = s
+		if p > 1 {
+			v.Set("page", strconv.Itoa(p))
+		}
+		return URLFor("/collections/view?" + v.Encode())
 	}
 
-	topCity := ""
-	topCount := 0
-	for city, count := range cityCount {
-		if count > topCount {
-			topCity = city
-			topCount = count
-		}
+	pager := Pager{Page: page, PerPage: perPage, TotalPages: totalPages, Total: matching}
+	if page > 1 {
+		pager.PrevURL = pageURL(page - 1)
+	}
+	if page < totalPages {
+		pager.NextURL = pageURL(page + 1)
 	}
 
 	data := struct {
-		Collection Collection
-		Tastings   []Tasting
-		AvgScore   string
-		TopCity    string
+		Collection  Collection
+		Tastings    []Tasting
+		Total       int
+		AvgScore    string
+		WeightedAvg string
+		TopCity     string
+		Query       string
+		Pager       Pager
 	}{
-		Collection: coll,
-		Tastings:   tastings,
-		AvgScore:   avgScore,
-		TopCity:    topCity,
+		Collection:  coll,
+		Tastings:    tastings,
+		Total:       total,
+		AvgScore:    avgs.Simple,
+		WeightedAvg: avgs.Weighted,
+		TopCity:     topCity,
+		Query:       q,
+		Pager:       pager,
 	}
 
 	if err := Tmpl.ExecuteTemplate(w, "collection.html", data); err != nil {
 		log.Println("Erreur template collection:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 	}
 }
 
 func AddCollection(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
@@ -225,7 +266,7 @@ func AddCollection(w http.ResponseWriter, r *http.Request) {
 		emoji = "📁"
 	}
 	if name == "" {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
@@ -235,17 +276,18 @@ func AddCollection(w http.ResponseWriter, r *http.Request) {
 	if _, err := DB.ExecContext(ctx, `INSERT INTO collections (name, emoji) VALUES ($1, $2)`, name, emoji); err != nil {
 		log.Println("Erreur création collection:", err)
 	}
-	http.Redirect(w, r, "/", http.StatusFound)
+	InvalidateCollectionsCache()
+	http.Redirect(w, r, URLFor("/"), http.StatusFound)
 }
 
 func AddToCollection(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
 	if err := r.ParseForm(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "parse error"})
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
 		return
 	}
 
@@ -253,18 +295,14 @@ func AddToCollection(w http.ResponseWriter, r *http.Request) {
 	tastingID := strings.TrimSpace(r.FormValue("tasting_id"))
 
 	// Déterminer si la requête est AJAX
-	isAjax := strings.Contains(r.Header.Get("Accept"), "application/json") ||
-		strings.Contains(r.Header.Get("X-Requested-With"), "XMLHttpRequest")
+	isAjax := wantsJSON(r)
 
 	if collID == "" || tastingID == "" {
 		if isAjax {
-			writeJSON(w, http.StatusBadRequest, map[string]any{
-				"ok":    false,
-				"error": "collection_id ou tasting_id manquant",
-			})
+			writeError(w, http.StatusBadRequest, "bad_request", "collection_id ou tasting_id manquant")
 			return
 		}
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
@@ -279,15 +317,13 @@ func AddToCollection(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Println("Erreur ajout collection:", err)
 		if isAjax {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{
-				"ok":    false,
-				"error": err.Error(),
-			})
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
 			return
 		}
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
+	InvalidateCollectionsCache()
 
 	// Récupérer le nom + emoji pour feedback
 	var collName, collEmoji string
@@ -314,13 +350,13 @@ func AddToCollection(w http.ResponseWriter, r *http.Request) {
 	if strings.Contains(referer, "/collections/view") {
 		http.Redirect(w, r, referer, http.StatusFound)
 	} else {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 	}
 }
 
 func RemoveFromCollection(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 	_ = r.ParseForm()
@@ -332,14 +368,15 @@ func RemoveFromCollection(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), collectionsDBTimeout)
 		defer cancel()
 		_, _ = DB.ExecContext(ctx, `DELETE FROM collection_tastings WHERE collection_id=$1 AND tasting_id=$2`, collID, tastingID)
+		InvalidateCollectionsCache()
 	}
 
-	http.Redirect(w, r, "/collections/view?id="+collID, http.StatusFound)
+	http.Redirect(w, r, URLFor("/collections/view?id="+url.QueryEscape(collID)), http.StatusFound)
 }
 
 func DeleteCollection(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 	_ = r.ParseForm()
@@ -349,61 +386,158 @@ func DeleteCollection(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), collectionsDBTimeout)
 		defer cancel()
 
-		// supprimer d'abord les liaisons (si pas de CASCADE en DB)
-		_, _ = DB.ExecContext(ctx, `DELETE FROM collection_tastings WHERE collection_id=$1`, id)
-		_, _ = DB.ExecContext(ctx, `DELETE FROM collections WHERE id=$1`, id)
+		// supprimer d'abord les liaisons (si pas de CASCADE en DB), le tout atomique
+		err := withTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM collection_tastings WHERE collection_id=$1`, id); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE id=$1`, id)
+			return err
+		})
+		if err != nil {
+			log.Println("Erreur suppression collection:", err)
+		}
+		InvalidateCollectionsCache()
 	}
 
-	http.Redirect(w, r, "/", http.StatusFound)
+	http.Redirect(w, r, URLFor("/"), http.StatusFound)
 }
 
-// writeJSON centralise l'encodage JSON (plus propre que des fmt.Fprintf avec échappement maison)
+// Longueur max d'un nom de collection (en caractères)
+const maxCollectionNameLength = 100
 
-func CollectionsForTasting(w http.ResponseWriter, r *http.Request) {
-	tid := strings.TrimSpace(r.URL.Query().Get("tasting_id"))
-	if tid == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]any{
-			"ok":    false,
-			"error": "tasting_id manquant",
-		})
+// collectionNameTaken : une autre collection porte déjà ce nom (insensible à la casse),
+// comme à l'import où les collections sont retrouvées par nom.
+func collectionNameTaken(ctx context.Context, tx *sql.Tx, name string) (bool, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM collections WHERE lower(name) = lower($1))`, name).Scan(&taken)
+	return taken, err
+}
+
+// CopyCollection duplique une collection (nom, emoji et fiches liées) sous un nouveau nom.
+// Sans nom : « <source> (copie) ». Une source vide donne une collection vide.
+// POST /collections/copy  id=...&name=...
+func CopyCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		renderError(w, r, http.StatusMethodNotAllowed, "Méthode non autorisée.")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		renderError(w, r, http.StatusBadRequest, "Formulaire illisible.")
+		return
+	}
+
+	srcID := strings.TrimSpace(r.FormValue("id"))
+	if srcID == "" {
+		renderError(w, r, http.StatusBadRequest, "Identifiant de collection manquant.")
+		return
+	}
+	name, err := validateField("Nom de la collection", r.FormValue("name"), maxCollectionNameLength)
+	if err != nil {
+		renderError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), collectionsDBTimeout)
 	defer cancel()
 
+	var newID string
+	var copied int64
+	var taken bool
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		var srcName, emoji string
+		if err := tx.QueryRowContext(ctx, `SELECT name, COALESCE(emoji,'') FROM collections WHERE id = $1`, srcID).
+			Scan(&srcName, &emoji); err != nil {
+			return err
+		}
+		if name == "" {
+			name = srcName + " (copie)"
+		}
+		if taken, err = collectionNameTaken(ctx, tx, name); err != nil || taken {
+			return err
+		}
+
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO collections (name, emoji) VALUES ($1, $2) RETURNING id`, name, emoji,
+		).Scan(&newID); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO collection_tastings (collection_id, tasting_id)
+			SELECT $1, tasting_id FROM collection_tastings WHERE collection_id = $2
+		`, newID, srcID)
+		if err != nil {
+			return err
+		}
+		copied, _ = res.RowsAffected()
+		return nil
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		renderError(w, r, http.StatusNotFound, "Cette collection n'existe pas (ou plus).")
+		return
+	case err != nil:
+		log.Println("Erreur copie collection:", err)
+		renderError(w, r, http.StatusInternalServerError, "La collection n'a pas pu être copiée, réessaie dans un instant.")
+		return
+	case taken:
+		renderError(w, r, http.StatusConflict, fmt.Sprintf("Une collection s'appelle déjà « %s ».", name))
+		return
+	}
+	InvalidateCollectionsCache()
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusCreated, map[string]any{"ok": true, "id": newID, "name": name, "copied": copied})
+		return
+	}
+	http.Redirect(w, r, URLFor("/collections/view?id="+url.QueryEscape(newID)), http.StatusSeeOther)
+}
+
+// writeJSON centralise l'encodage JSON (plus propre que des fmt.Fprintf avec échappement maison)
+
+// collectionsOfTasting renvoie les collections contenant une dégustation.
+func collectionsOfTasting(ctx context.Context, tastingID string) ([]Collection, error) {
 	rows, err := DB.QueryContext(ctx, `
 		SELECT c.id, c.name, COALESCE(c.emoji,'📁')
 		FROM collections c
 		JOIN collection_tastings ct ON ct.collection_id = c.id
 		WHERE ct.tasting_id = $1
 		ORDER BY c.created_at DESC
-	`, tid)
+	`, tastingID)
 	if err != nil {
-		log.Println("Erreur CollectionsForTasting:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"ok":    false,
-			"error": "erreur serveur",
-		})
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
-	type miniColl struct {
-		ID    string `json:"id"`
-		Name  string `json:"name"`
-		Emoji string `json:"emoji"`
-	}
-
-	var out []miniColl
+	var out []Collection
 	for rows.Next() {
-		var c miniColl
+		var c Collection
 		if err := rows.Scan(&c.ID, &c.Name, &c.Emoji); err != nil {
-			log.Println("Scan miniColl:", err)
+			log.Println("Scan collection de la fiche:", err)
 			continue
 		}
 		out = append(out, c)
 	}
+	return out, rows.Err()
+}
+
+func CollectionsForTasting(w http.ResponseWriter, r *http.Request) {
+	tid := strings.TrimSpace(r.URL.Query().Get("tasting_id"))
+	if tid == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "tasting_id manquant")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), collectionsDBTimeout)
+	defer cancel()
+
+	out, err := collectionsOfTasting(ctx, tid)
+	if err != nil {
+		log.Println("Erreur CollectionsForTasting:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"ok":          true,
@@ -413,18 +547,18 @@ func CollectionsForTasting(w http.ResponseWriter, r *http.Request) {
 }
 func RemoveFromCollectionAJAX(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"ok": false, "error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "parse error"})
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
 		return
 	}
 
 	collID := strings.TrimSpace(r.FormValue("collection_id"))
 	tastingID := strings.TrimSpace(r.FormValue("tasting_id"))
 	if collID == "" || tastingID == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "collection_id ou tasting_id manquant"})
+		writeError(w, http.StatusBadRequest, "bad_request", "collection_id ou tasting_id manquant")
 		return
 	}
 
@@ -433,16 +567,81 @@ func RemoveFromCollectionAJAX(w http.ResponseWriter, r *http.Request) {
 
 	if _, err := DB.ExecContext(ctx, `DELETE FROM collection_tastings WHERE collection_id=$1 AND tasting_id=$2`, collID, tastingID); err != nil {
 		log.Println("RemoveFromCollectionAJAX:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "erreur serveur"})
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
 		return
 	}
+	InvalidateCollectionsCache()
 
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
+
+// ToggleCollectionMembership ajoute la fiche à la collection si elle n'y est pas,
+// l'en retire sinon, et renvoie le nouvel état. La ligne de la collection est
+// verrouillée : deux taps rapides s'appliquent l'un après l'autre.
+// POST /collections/toggle  collection_id=...&tasting_id=...
+func ToggleCollectionMembership(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	collID := strings.TrimSpace(r.FormValue("collection_id"))
+	tastingID := strings.TrimSpace(r.FormValue("tasting_id"))
+	if collID == "" || tastingID == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "collection_id ou tasting_id manquant")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), collectionsDBTimeout)
+	defer cancel()
+
+	var inCollection bool
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		var locked string
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM collections WHERE id = $1 FOR UPDATE`, collID).Scan(&locked); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx,
+			`DELETE FROM collection_tastings WHERE collection_id = $1 AND tasting_id = $2`, collID, tastingID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			return nil
+		}
+		inCollection = true
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO collection_tastings (collection_id, tasting_id) VALUES ($1, $2)`, collID, tastingID)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not_found", "collection introuvable")
+		return
+	}
+	if err != nil {
+		log.Println("Erreur bascule collection:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	InvalidateCollectionsCache()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":            true,
+		"collection_id": collID,
+		"tasting_id":    tastingID,
+		"in_collection": inCollection,
+	})
+}
+
 func GetCollectionsForTasting(w http.ResponseWriter, r *http.Request) {
 	tid := strings.TrimSpace(r.URL.Query().Get("tasting_id"))
 	if tid == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "tasting_id manquant"})
+		writeError(w, http.StatusBadRequest, "bad_request", "tasting_id manquant")
 		return
 	}
 
@@ -458,7 +657,7 @@ func GetCollectionsForTasting(w http.ResponseWriter, r *http.Request) {
 	`, tid)
 	if err != nil {
 		log.Println("Erreur GetCollectionsForTasting:", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "db error"})
+		writeError(w, http.StatusInternalServerError, "internal", "db error")
 		return
 	}
 	defer rows.Close()
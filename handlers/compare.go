@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Nombre max de dégustations comparées côte à côte
+const maxCompareIDs = 4
+
+// parseIDList découpe "1,2,3" en ids (vides ignorés, doublons retirés, ordre conservé).
+func parseIDList(raw string) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" || len(p) > 64 || seen[p] {
+			continue
+		}
+		seen[p] = true
+		ids = append(ids, p)
+	}
+	return ids
+}
+
+// Compare affiche plusieurs dégustations côte à côte.
+// GET /compare?ids=1,2,3
+func Compare(w http.ResponseWriter, r *http.Request) {
+	ids := parseIDList(r.URL.Query().Get("ids"))
+	if len(ids) == 0 {
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
+		return
+	}
+
+	truncated := false
+	if len(ids) > maxCompareIDs {
+		ids = ids[:maxCompareIDs]
+		truncated = true
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	found, err := queryTastings(ctx, "compare.tastings", `SELECT`+tastingSelectCols+`FROM tastings WHERE id::text = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		log.Println("Erreur requête compare:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
+
+	// Ordre = celui demandé dans l'URL
+	byID := make(map[string]Tasting, len(found))
+	for _, t := range found {
+		byID[t.ID] = t
+	}
+	var tastings []Tasting
+	var missing []string
+	for _, id := range ids {
+		if t, ok := byID[id]; ok {
+			tastings = append(tastings, t)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	data := struct {
+		Tastings  []Tasting
+		Missing   []string
+		Truncated bool
+		MaxIDs    int
+	}{
+		Tastings:  tastings,
+		Missing:   missing,
+		Truncated: truncated,
+		MaxIDs:    maxCompareIDs,
+	}
+
+	if err := Tmpl.ExecuteTemplate(w, "compare.html", data); err != nil {
+		log.Println("Erreur template compare:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+	}
+}
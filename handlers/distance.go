@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ─── Distances (haversine) ─────────────────────────────────────────────────
+
+const earthRadiusKm = 6371.0
+
+// Rayon par défaut / max pour la recherche "autour de moi"
+const (
+	defaultNearRadiusKm = 10.0
+	maxNearRadiusKm     = 200.0
+	maxNearResults      = 100
+)
+
+// haversineKm renvoie la distance (km) entre deux points GPS.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// roundKm arrondit à 2 décimales (lisible côté JSON)
+func roundKm(km float64) float64 {
+	return math.Round(km*100) / 100
+}
+
+type nearTasting struct {
+	Tasting
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// lonRanges découpe [lon-dLon, lon+dLon] en deux intervalles dans [-180, 180] quand
+// il franchit l'antiméridien (Fidji, Kamtchatka) ; sinon les deux sont identiques.
+func lonRanges(lon, dLon float64) (west, east [2]float64) {
+	lo, hi := lon-dLon, lon+dLon
+	switch {
+	case dLon >= 180:
+		lo, hi = -180, 180
+	case lo < -180:
+		return [2]float64{-180, hi}, [2]float64{lo + 360, 180}
+	case hi > 180:
+		return [2]float64{-180, hi - 360}, [2]float64{lo, 180}
+	}
+	return [2]float64{lo, hi}, [2]float64{lo, hi}
+}
+
+// NearTastings renvoie les dégustations autour d'un point, triées par distance.
+// GET /api/v1/tastings/near?lat=48.85&lon=2.35&radius_km=5
+func NearTastings(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(q.Get("lat")), 64)
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(q.Get("lon")), 64)
+	if errLat != nil || errLon != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "lat et lon requis")
+		return
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		writeError(w, http.StatusBadRequest, "bad_request", "lat/lon invalides")
+		return
+	}
+
+	radius := defaultNearRadiusKm
+	if s := strings.TrimSpace(q.Get("radius_km")); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f <= 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "radius_km invalide")
+			return
+		}
+		radius = math.Min(f, maxNearRadiusKm)
+	}
+
+	// Pré-filtre "bounding box" côté SQL, le calcul exact se fait en Go.
+	dLat := radius / 111.0
+	dLon := radius / (111.0 * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	if lat+dLat >= 90 || lat-dLat <= -90 {
+		dLon = 180 // cercle autour du pôle : toutes les longitudes
+	}
+	west, east := lonRanges(lon, dLon)
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `SELECT`+tastingSelectCols+`FROM tastings
+		WHERE `+notArchived+` AND latitude IS NOT NULL AND longitude IS NOT NULL
+		  AND latitude BETWEEN $1 AND $2
+		  AND (longitude BETWEEN $3 AND $4 OR longitude BETWEEN $5 AND $6)`,
+		lat-dLat, lat+dLat, west[0], west[1], east[0], east[1],
+	)
+	if err != nil {
+		log.Println("Erreur requête near:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	defer rows.Close()
+
+	aMap := aromaNameMap()
+
+	out := make([]nearTasting, 0)
+	for rows.Next() {
+		t, err := scanTasting(rows, aMap)
+		if err != nil {
+			log.Println("Erreur scan near:", err)
+			continue
+		}
+		if t.Latitude == nil || t.Longitude == nil {
+			continue
+		}
+		d := haversineKm(lat, lon, *t.Latitude, *t.Longitude)
+		if d > radius {
+			continue
+		}
+		out = append(out, nearTasting{Tasting: t, DistanceKm: roundKm(d)})
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur rows near:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].DistanceKm < out[j].DistanceKm })
+	if len(out) > maxNearResults {
+		out = out[:maxNearResults]
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":        true,
+		"radius_km": radius,
+		"tastings":  out,
+	})
+}
+
+// ─── Parcours (route summary) ──────────────────────────────────────────────
+
+const maxRouteIDs = 100
+
+type routeLeg struct {
+	FromID     string  `json:"from_id"`
+	ToID       string  `json:"to_id"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+type routeSkipped struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// RouteSummary calcule la distance parcourue pour une liste ordonnée de dégustations.
+// POST /api/v1/route  {"ids": [12, 15, 18]}
+func RouteSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	var payload struct {
+		IDs []any `json:"ids"`
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.UseNumber()
+	if err := dec.Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "JSON invalide")
+		return
+	}
+
+	ids := make([]string, 0, len(payload.IDs))
+	for _, raw := range payload.IDs {
+		id := strings.TrimSpace(fmt.Sprint(raw))
+		if id == "" || len(id) > 64 {
+			writeError(w, http.StatusBadRequest, "bad_request", "id invalide")
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "ids requis")
+		return
+	}
+	if len(ids) > maxRouteIDs {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("max %d ids", maxRouteIDs))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `SELECT id::text, latitude, longitude FROM tastings WHERE id::text = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		log.Println("Erreur requête route:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	defer rows.Close()
+
+	type point struct{ lat, lon sql.NullFloat64 }
+	points := map[string]point{}
+	for rows.Next() {
+		var id string
+		var p point
+		if err := rows.Scan(&id, &p.lat, &p.lon); err != nil {
+			log.Println("Erreur scan route:", err)
+			continue
+		}
+		points[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur rows route:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	legs := make([]routeLeg, 0)
+	skipped := make([]routeSkipped, 0)
+	total := 0.0
+
+	var prevID string
+	var prevLat, prevLon float64
+	hasPrev := false
+
+	for _, id := range ids {
+		p, ok := points[id]
+		if !ok {
+			skipped = append(skipped, routeSkipped{ID: id, Reason: "dégustation introuvable"})
+			continue
+		}
+		if !p.lat.Valid || !p.lon.Valid {
+			skipped = append(skipped, routeSkipped{ID: id, Reason: "pas de coordonnées"})
+			continue
+		}
+		if hasPrev {
+			d := haversineKm(prevLat, prevLon, p.lat.Float64, p.lon.Float64)
+			total += d
+			legs = append(legs, routeLeg{FromID: prevID, ToID: id, DistanceKm: roundKm(d)})
+		}
+		prevID, prevLat, prevLon, hasPrev = id, p.lat.Float64, p.lon.Float64, true
+	}
+
+	resp := map[string]any{
+		"ok":       true,
+		"legs":     legs,
+		"total_km": roundKm(total),
+		"skipped":  skipped,
+	}
+	if len(legs) == 0 {
+		resp["note"] = "au moins deux dégustations géolocalisées sont nécessaires"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ─── Heatmap (points agrégés) ──────────────────────────────────────────────
+
+// Précision d'agrégation = nombre de décimales (2 ≈ 1 km, 3 ≈ 100 m).
+// Défaut surchargeable via MAP_HEATMAP_PRECISION.
+const (
+	defaultHeatmapPrecision = 2
+	maxHeatmapPrecision     = 4
+)
+
+// HeatmapPrecision : précision sans paramètre ?precision (MAP_HEATMAP_PRECISION)
+var HeatmapPrecision = defaultHeatmapPrecision
+
+type heatPoint struct {
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	Weight int     `json:"weight"`
+}
+
+func parseHeatmapPrecision(raw string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("précision invalide : %q (entier de 0 à %d)", raw, maxHeatmapPrecision)
+	}
+	return min(n, maxHeatmapPrecision), nil
+}
+
+// SetHeatmapPrecision lit MAP_HEATMAP_PRECISION (vide : défaut).
+// En cas d'erreur, la valeur courante est conservée.
+func SetHeatmapPrecision(s string) error {
+	if strings.TrimSpace(s) == "" {
+		HeatmapPrecision = defaultHeatmapPrecision
+		return nil
+	}
+	n, err := parseHeatmapPrecision(s)
+	if err != nil {
+		return fmt.Errorf("MAP_HEATMAP_PRECISION : %w", err)
+	}
+	HeatmapPrecision = n
+	return nil
+}
+
+// heatmapPrecision : ?precision de la requête, HeatmapPrecision si absent ou illisible.
+func heatmapPrecision(raw string) int {
+	if n, err := parseHeatmapPrecision(raw); err == nil {
+		return n
+	}
+	return HeatmapPrecision
+}
+
+// MapHeatmap renvoie les dégustations agrégées par coordonnées arrondies,
+// pondérées par le nombre de fiches (couche "heat" côté carte).
+// GET /api/v1/map/heatmap?precision=2
+func MapHeatmap(w http.ResponseWriter, r *http.Request) {
+	precision := heatmapPrecision(r.URL.Query().Get("precision"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT round(latitude::numeric, $1)::float8, round(longitude::numeric, $1)::float8, COUNT(*)
+		FROM tastings
+		WHERE `+notArchived+` AND latitude IS NOT NULL AND longitude IS NOT NULL
+		GROUP BY 1, 2
+		ORDER BY 3 DESC
+	`, precision)
+	if err != nil {
+		log.Println("Erreur requête heatmap:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	defer rows.Close()
+
+	points := make([]heatPoint, 0)
+	maxWeight := 0
+	for rows.Next() {
+		var p heatPoint
+		if err := rows.Scan(&p.Lat, &p.Lng, &p.Weight); err != nil {
+			log.Println("Erreur scan heatmap:", err)
+			continue
+		}
+		maxWeight = max(maxWeight, p.Weight)
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur rows heatmap:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":         true,
+		"precision":  precision,
+		"max_weight": maxWeight,
+		"points":     points,
+	})
+}
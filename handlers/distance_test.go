@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHeatmapPrecision(t *testing.T) {
+	saved := HeatmapPrecision
+	t.Cleanup(func() { HeatmapPrecision = saved })
+
+	if err := SetHeatmapPrecision("abc"); err == nil || HeatmapPrecision != saved {
+		t.Errorf("valeur invalide : err = %v, précision %d (want %d conservée)", err, HeatmapPrecision, saved)
+	}
+	if err := SetHeatmapPrecision(" 3 "); err != nil || HeatmapPrecision != 3 {
+		t.Fatalf("MAP_HEATMAP_PRECISION=3 : err = %v, précision %d", err, HeatmapPrecision)
+	}
+
+	tests := []struct {
+		raw  string
+		want int
+	}{
+		{"", 3},
+		{"abc", 3},
+		{"-1", 3},
+		{"0", 0},
+		{"1", 1},
+		{"9", maxHeatmapPrecision},
+	}
+	for _, tt := range tests {
+		if got := heatmapPrecision(tt.raw); got != tt.want {
+			t.Errorf("heatmapPrecision(%q) = %d, want %d", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestLonRanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		lon, dLon  float64
+		west, east [2]float64
+	}{
+		{"sans franchissement", 2, 1, [2]float64{1, 3}, [2]float64{1, 3}},
+		{"bord est", 179.5, 1, [2]float64{-180, -179.5}, [2]float64{178.5, 180}},
+		{"bord ouest", -179.5, 1, [2]float64{-180, -178.5}, [2]float64{179.5, 180}},
+		{"limite exacte", 179, 1, [2]float64{178, 180}, [2]float64{178, 180}},
+		{"tour complet", 0, 200, [2]float64{-180, 180}, [2]float64{-180, 180}},
+	}
+	const eps = 1e-9
+	same := func(a, b [2]float64) bool { return math.Abs(a[0]-b[0]) < eps && math.Abs(a[1]-b[1]) < eps }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			west, east := lonRanges(tt.lon, tt.dLon)
+			if !same(west, tt.west) || !same(east, tt.east) {
+				t.Errorf("lonRanges(%v, %v) = %v, %v, want %v, %v", tt.lon, tt.dLon, west, east, tt.west, tt.east)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ─── Live updates (Server-Sent Events) ─────────────────────────────────────
+
+const sseHeartbeat = 30 * time.Second
+
+// TastingEvent est poussé aux clients connectés à /events après chaque mutation.
+type TastingEvent struct {
+	Type string `json:"type"` // tasting.added | tasting.updated | tasting.deleted
+	ID   string `json:"id"`
+}
+
+type eventBroker struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+var events = &eventBroker{clients: make(map[chan []byte]struct{})}
+
+func (b *eventBroker) subscribe() chan []byte {
+	ch := make(chan []byte, 8)
+	b.mu.Lock()
+	b.clients[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch chan []byte) {
+	b.mu.Lock()
+	delete(b.clients, ch)
+	b.mu.Unlock()
+}
+
+// broadcast n'attend jamais : un client trop lent perd simplement l'événement.
+func (b *eventBroker) broadcast(msg []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// publishTastingEvent notifie les clients connectés (best-effort).
+func publishTastingEvent(kind, id string) {
+	msg, err := json.Marshal(TastingEvent{Type: kind, ID: id})
+	if err != nil {
+		log.Println("Erreur encodage event:", err)
+		return
+	}
+	events.broadcast(msg)
+}
+
+// Events ouvre un flux SSE : un message JSON par mutation, heartbeat toutes les 30s.
+// GET /events
+func Events(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// Connexion longue : on lève le WriteTimeout global du serveur
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ch := events.subscribe()
+	defer events.unsubscribe(ch)
+
+	// Indique au navigateur le délai de reconnexion
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case msg := <-ch:
+			if _, err := fmt.Fprintf(w, "event: tasting\ndata: %s\n\n", msg); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
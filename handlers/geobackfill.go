@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── Géocodage des anciennes fiches (ville sans coordonnées) ───────────────
+//
+// Une recherche Nominatim par ville distincte ; toutes les fiches de cette ville
+// sans coordonnées reçoivent le résultat. Les villes introuvables ou ambiguës
+// sont ignorées (et journalisées). Reprise : les villes sont traitées par ordre
+// alphabétique, le curseur "after" renvoyé par un passage sert au suivant.
+
+const (
+	DefaultGeocodeBatch = 20
+	// Politique d'usage Nominatim : 1 requête par seconde au maximum
+	geocodeDelay = 1100 * time.Millisecond
+	// Deux lieux homonymes plus éloignés que ça, d'importance proche : ambigu
+	geocodeAmbiguousKm         = 50.0
+	geocodeAmbiguousImportance = 0.1
+)
+
+// GeocodeStats résume un passage de géocodage.
+type GeocodeStats struct {
+	Cities    int    // villes traitées
+	Updated   int    // fiches mises à jour
+	Skipped   int    // villes introuvables, ambiguës ou en erreur
+	Next      string // curseur du passage suivant ("" : plus rien après)
+	Remaining int    // fiches avec ville mais sans coordonnées (ignorées comprises)
+}
+
+const missingCoordsClause = `COALESCE(trim(city),'') <> '' AND (latitude IS NULL OR longitude IS NULL)`
+
+// GeocodeMissingCities géocode au plus limit villes (après le curseur after, limit <= 0 : toutes).
+func GeocodeMissingCities(ctx context.Context, after string, limit int) (GeocodeStats, error) {
+	st := GeocodeStats{}
+
+	query := `SELECT DISTINCT trim(city) AS c FROM tastings WHERE ` + missingCoordsClause + ` AND trim(city) > $1 ORDER BY c`
+	args := []any{after}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return st, err
+	}
+	var cities []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			rows.Close()
+			return st, err
+		}
+		cities = append(cities, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+
+	for i, city := range cities {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return st, ctx.Err()
+			case <-time.After(geocodeDelay):
+			}
+		}
+		st.Cities++
+
+		lat, lon, err := geocodeCity(ctx, city)
+		if err != nil {
+			st.Skipped++
+			st.Next = city
+			log.Printf("Géocodage %d/%d : %q ignorée (%v)", i+1, len(cities), city, err)
+			continue
+		}
+		n, err := setCityCoords(ctx, city, lat, lon)
+		if err != nil {
+			return st, err
+		}
+		st.Updated += n
+		st.Next = city
+		log.Printf("Géocodage %d/%d : %q -> %.5f, %.5f (%d fiches)", i+1, len(cities), city, lat, lon, n)
+	}
+	// Lot incomplet : toutes les villes restantes ont été vues
+	if limit <= 0 || len(cities) < limit {
+		st.Next = ""
+	}
+
+	err = DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tastings WHERE `+missingCoordsClause).Scan(&st.Remaining)
+	return st, err
+}
+
+// nominatimPlace = champs utiles d'un résultat de recherche Nominatim.
+type nominatimPlace struct {
+	Lat         string  `json:"lat"`
+	Lon         string  `json:"lon"`
+	Class       string  `json:"class"`
+	AddressType string  `json:"addresstype"`
+	Importance  float64 `json:"importance"`
+}
+
+// Résultats retenus comme ville (pas une rue ni un commerce homonyme)
+var settlementTypes = map[string]bool{
+	"city": true, "town": true, "village": true, "municipality": true, "hamlet": true, "suburb": true,
+}
+
+// geocodeCity renvoie les coordonnées d'une ville ; erreur si introuvable ou ambiguë.
+func geocodeCity(ctx context.Context, city string) (float64, float64, error) {
+	body, err := fetchNominatim(ctx, nominatimSearchURL(city, 5, geoCountryCodes))
+	if err != nil {
+		return 0, 0, err
+	}
+	var results []nominatimPlace
+	if err := json.Unmarshal(body, &results); err != nil {
+		return 0, 0, err
+	}
+
+	type place struct{ lat, lon, importance float64 }
+	var places []place
+	for _, res := range results {
+		if res.Class != "place" && !settlementTypes[res.AddressType] {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(res.Lat, 64)
+		lon, err2 := strconv.ParseFloat(res.Lon, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		places = append(places, place{lat, lon, res.Importance})
+	}
+	if len(places) == 0 {
+		return 0, 0, fmt.Errorf("introuvable")
+	}
+
+	// Nominatim trie par importance : le premier l'emporte s'il domine nettement
+	top := places[0]
+	for _, p := range places[1:] {
+		if top.importance-p.importance < geocodeAmbiguousImportance &&
+			haversineKm(top.lat, top.lon, p.lat, p.lon) > geocodeAmbiguousKm {
+			return 0, 0, fmt.Errorf("ambiguë (%d lieux)", len(places))
+		}
+	}
+	return top.lat, top.lon, nil
+}
+
+// setCityCoords renseigne les coordonnées des fiches de city qui n'en ont pas.
+func setCityCoords(ctx context.Context, city string, lat, lon float64) (int, error) {
+	rows, err := DB.QueryContext(ctx, `
+		UPDATE tastings SET latitude=$1, longitude=$2
+		WHERE trim(city)=$3 AND (latitude IS NULL OR longitude IS NULL)
+		RETURNING id
+	`, lat, lon, city)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return n, err
+		}
+		publishTastingEvent("tasting.updated", id)
+		n++
+	}
+	return n, rows.Err()
+}
+
+// GeocodeCities géocode un lot de villes sans coordonnées (à rappeler avec after=next tant que next != "").
+// POST /admin/geocode[?limit=20&after=Lyon]
+func GeocodeCities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	limit := min(parsePositiveInt(r.URL.Query().Get("limit"), DefaultGeocodeBatch), 100)
+	after := strings.TrimSpace(r.URL.Query().Get("after"))
+
+	// Une ville par seconde : on repousse le WriteTimeout global du serveur
+	batchTimeout := time.Duration(limit)*(geocodeDelay+geoHTTPClient.Timeout) + time.Minute
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(batchTimeout))
+
+	ctx, cancel := detachContext(r, batchTimeout)
+	defer cancel()
+
+	st, err := GeocodeMissingCities(ctx, after, limit)
+	if err != nil {
+		log.Println("Erreur géocodage villes:", err)
+		// Enveloppe commune + curseur : le lot suivant peut reprendre après l'échec
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"ok":    false,
+			"error": map[string]string{"code": "internal", "message": "erreur serveur"},
+			"next":  st.Next,
+		})
+		return
+	}
+
+	log.Printf("Géocodage : %d villes, %d fiches mises à jour, %d ignorées, %d fiches restantes",
+		st.Cities, st.Updated, st.Skipped, st.Remaining)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok": true, "cities": st.Cities, "updated": st.Updated, "skipped": st.Skipped,
+		"next": st.Next, "remaining": st.Remaining,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// ─── Recadrage des photos trop allongées (PHOTO_MAX_ASPECT) ────────────────
+
+// photoMaxAspect = rapport max grand côté / petit côté des images de carte
+// (0 : pas de recadrage). Ex : 2 pour limiter à 2:1 (ou 1:2 en portrait).
+var photoMaxAspect float64
+
+// SetPhotoMaxAspect lit PHOTO_MAX_ASPECT ("2:1", "3/2" ou "1.5" ; vide ou 0 : désactivé).
+// En cas d'erreur, la valeur courante est conservée.
+func SetPhotoMaxAspect(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		photoMaxAspect = 0
+		return nil
+	}
+
+	num, den, hasDen := strings.Cut(strings.ReplaceAll(s, "/", ":"), ":")
+	a, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	b := 1.0
+	if err == nil && hasDen {
+		b, err = strconv.ParseFloat(strings.TrimSpace(den), 64)
+	}
+	if err != nil || a < 0 || b <= 0 {
+		return fmt.Errorf("PHOTO_MAX_ASPECT invalide : %q (ex : 2:1)", s)
+	}
+
+	ratio := a / b
+	if ratio > 0 && ratio < 1 {
+		ratio = 1 / ratio // 1:2 et 2:1 : même limite (1:1 : vignettes carrées)
+	}
+	photoMaxAspect = ratio
+	return nil
+}
+
+// cropToMaxAspect recadre au centre une image plus allongée que maxAspect
+// (dans un sens ou dans l'autre) ; sinon la renvoie telle quelle.
+func cropToMaxAspect(img image.Image, maxAspect float64) image.Image {
+	if maxAspect <= 0 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+
+	rect := b
+	switch {
+	case float64(w) > float64(h)*maxAspect:
+		nw := int(float64(h) * maxAspect)
+		x0 := b.Min.X + (w-nw)/2
+		rect = image.Rect(x0, b.Min.Y, x0+nw, b.Max.Y)
+	case float64(h) > float64(w)*maxAspect:
+		nh := int(float64(w) * maxAspect)
+		y0 := b.Min.Y + (h-nh)/2
+		rect = image.Rect(b.Min.X, y0, b.Max.X, y0+nh)
+	default:
+		return img
+	}
+
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	// Type d'image sans SubImage (rare) : copie pixel par pixel
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			dst.Set(x-rect.Min.X, y-rect.Min.Y, img.At(x, y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+)
+
+// ─── Format de sortie des photos (IMAGE_OUTPUT) ────────────────────────────
+
+// imageEncoder : encodage d'une photo avant envoi au storage.
+type imageEncoder struct {
+	Ext         string // extension du fichier stocké
+	ContentType string
+	Encode      func(w io.Writer, img image.Image) error
+}
+
+// Encodeurs disponibles. "webp" n'existe qu'avec -tags webp (imageformat_webp.go).
+var imageEncoders = map[string]imageEncoder{
+	"jpeg": {
+		Ext:         ".jpg",
+		ContentType: "image/jpeg",
+		Encode: func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: JpegQuality})
+		},
+	},
+}
+
+// JPEG par défaut : lisible partout
+const defaultImageOutput = "jpeg"
+
+var imageOutput = imageEncoders[defaultImageOutput]
+
+// SetImageOutput choisit le format des photos uploadées (IMAGE_OUTPUT=jpeg|webp).
+// En cas d'erreur, le format courant est conservé.
+func SetImageOutput(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "":
+		name = defaultImageOutput
+	case "jpg":
+		name = "jpeg"
+	}
+
+	enc, ok := imageEncoders[name]
+	if !ok {
+		if name == "webp" {
+			return errors.New("IMAGE_OUTPUT=webp : binaire compilé sans encodeur WebP (go build -tags webp)")
+		}
+		return fmt.Errorf("IMAGE_OUTPUT inconnu : %q (jpeg ou webp)", name)
+	}
+	imageOutput = enc
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build webp
+
+package handlers
+
+import (
+	"image"
+	"io"
+
+	"github.com/gen2brain/webp"
+)
+
+// Encodeur WebP sans cgo (libwebp compilée en WASM), à activer explicitement
+// (dépendance déjà déclarée dans go.mod) :
+//
+//	go build -tags webp
+//
+// puis IMAGE_OUTPUT=webp.
+
+// Qualité WebP : ~même rendu que JPEG 80, fichier nettement plus léger
+const WebPQuality = 75
+
+func init() {
+	imageEncoders["webp"] = imageEncoder{
+		Ext:         ".webp",
+		ContentType: "image/webp",
+		Encode: func(w io.Writer, img image.Image) error {
+			return webp.Encode(w, img, webp.Options{Quality: WebPQuality})
+		},
+	}
+}
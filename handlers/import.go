@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── Import CSV ────────────────────────────────────────────────────────────
+
+const (
+	MaxImportSize = 5 << 20 // 5MB
+	maxImportRows = 5000
+)
+
+type importRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type importRow struct {
+	line        int
+	productName string
+	maker       string
+	city        string
+	score       float64
+	notes       string
+	aromaIDs    []int
+	newAromas   []string // arômes inconnus (créés si create_aromas=1)
+	lat, lon    *float64
+}
+
+// parseImportHeader associe chaque colonne à son index (insensible à la casse).
+// Colonnes lues : product_name (obligatoire), maker, city, score, notes, aromas, lat, lon.
+func parseImportHeader(header []string) (map[string]int, error) {
+	cols := map[string]int{}
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if name == "" {
+			continue
+		}
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("colonne en double : %s", name)
+		}
+		cols[name] = i
+	}
+	if _, ok := cols["product_name"]; !ok {
+		return nil, errors.New("colonne product_name obligatoire")
+	}
+	return cols, nil
+}
+
+// splitImportList découpe une cellule multi-valeurs ("fruité; boisé" ou "fruité|boisé").
+func splitImportList(s string) []string {
+	var out []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '|' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func parseImportCoord(s string, limit float64) (*float64, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", "."))
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < -limit || f > limit {
+		return nil, errors.New("coordonnée invalide")
+	}
+	return &f, nil
+}
+
+// parseImportRow valide une ligne et résout les arômes par nom (ResolveAromaIDs).
+func parseImportRow(line int, rec []string, cols map[string]int) (row importRow, err error) {
+	get := func(name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	row.line = line
+	if row.productName, err = validateField("Nom du produit", get("product_name"), MaxProductNameLength); err != nil {
+		return
+	}
+	if row.productName == "" {
+		err = errors.New("product_name vide")
+		return
+	}
+	if row.maker, err = validateField("Maker", get("maker"), MaxMakerLength); err != nil {
+		return
+	}
+	if row.city, err = validateField("Ville", get("city"), MaxCityLength); err != nil {
+		return
+	}
+
+	if s := strings.ReplaceAll(get("score"), ",", "."); s != "" {
+		f, perr := strconv.ParseFloat(s, 64)
+		if perr != nil || f < 0 || f > 10 {
+			err = fmt.Errorf("score invalide : %q (attendu entre 0 et 10)", s)
+			return
+		}
+		row.score = f
+	}
+
+	row.notes = sanitizeText(get("notes"), MaxNotesLength)
+
+	if row.lat, err = parseImportCoord(get("lat"), 90); err != nil {
+		err = errors.New("lat invalide")
+		return
+	}
+	if row.lon, err = parseImportCoord(get("lon"), 180); err != nil {
+		err = errors.New("lon invalide")
+		return
+	}
+	if (row.lat == nil) != (row.lon == nil) {
+		err = errors.New("lat et lon doivent être renseignées ensemble")
+		return
+	}
+
+	row.aromaIDs, row.newAromas = ResolveAromaIDs(splitImportList(get("aromas")))
+	return
+}
+
+// ImportCSV importe des dégustations depuis un CSV (champ multipart "file").
+// Les lignes invalides sont signalées (numéro + raison) sans bloquer les autres ;
+// seul un en-tête invalide fait échouer tout l'import.
+// dry_run=1 : même lecture et mêmes contrôles, mais rien n'est écrit (aperçu).
+// REQUIRE_PHOTO / REQUIRE_LOCATION ne s'appliquent pas : le CSV n'a pas de colonne
+// photo, et un historique importé garde ses fiches sans lieu.
+// POST /import/csv
+func ImportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportSize+(1<<20))
+	if err := r.ParseMultipartForm(MaxImportSize); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "fichier trop volumineux ou formulaire invalide")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "fichier CSV manquant (champ file)")
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	// Séparateur "," par défaut ; sep=";" pour un export Excel FR
+	if sep := r.FormValue("sep"); sep == ";" || sep == "\t" {
+		reader.Comma = rune(sep[0])
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "en-tête CSV illisible")
+		return
+	}
+	cols, err := parseImportHeader(header)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	// create_aromas=1 : les arômes inconnus sont créés (famille "Import") au lieu d'être ignorés
+	createUnknown := parseBoolParam(r.FormValue("create_aromas"))
+	dryRun := parseBoolParam(r.FormValue("dry_run"))
+
+	rowErrors := make([]importRowError, 0)
+	warnings := make([]importRowError, 0)
+	var rows []importRow
+	unknownAromas := make([]string, 0)
+	seenUnknown := map[string]bool{}
+
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			line := 0
+			if errors.As(err, &perr) {
+				line = perr.StartLine
+			}
+			rowErrors = append(rowErrors, importRowError{Line: line, Error: "ligne CSV illisible"})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows)+len(rowErrors) >= maxImportRows {
+			writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("max %d lignes par import", maxImportRows))
+			return
+		}
+
+		row, err := parseImportRow(line, rec, cols)
+		if err != nil {
+			rowErrors = append(rowErrors, importRowError{Line: line, Error: err.Error()})
+			continue
+		}
+		for _, name := range row.newAromas {
+			if k := aromaKey(name); !seenUnknown[k] {
+				seenUnknown[k] = true
+				unknownAromas = append(unknownAromas, name)
+			}
+		}
+		if len(row.newAromas) > 0 && !createUnknown {
+			warnings = append(warnings, importRowError{Line: line, Error: "arômes inconnus ignorés : " + strings.Join(row.newAromas, ", ")})
+			row.newAromas = nil
+		}
+		rows = append(rows, row)
+	}
+
+	if dryRun {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":             true,
+			"dry_run":        true,
+			"to_import":      len(rows),
+			"errors":         rowErrors,
+			"warnings":       warnings,
+			"unknown_aromas": unknownAromas,
+			"create_aromas":  createUnknown,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	ids, err := insertImportRows(ctx, rows)
+	if err != nil {
+		log.Println("Erreur import CSV:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur, rien n'a été importé")
+		return
+	}
+	if createUnknown {
+		InvalidateAromaCache()
+	}
+
+	for _, id := range ids {
+		publishTastingEvent("tasting.added", id)
+	}
+
+	log.Printf("Import CSV : %d dégustations, %d lignes en erreur", len(ids), len(rowErrors))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":       true,
+		"imported": len(ids),
+		"errors":   rowErrors,
+		"warnings": warnings,
+	})
+}
+
+// insertImportRows insère toutes les lignes valides dans une seule transaction
+// (création des arômes inconnus comprise).
+func insertImportRows(ctx context.Context, rows []importRow) ([]string, error) {
+	ids := make([]string, 0, len(rows))
+	if len(rows) == 0 {
+		return ids, nil
+	}
+
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		var newNames []string
+		for _, row := range rows {
+			newNames = append(newNames, row.newAromas...)
+		}
+		created, err := createAromas(ctx, tx, newNames, importAromaFamily)
+		if err != nil {
+			return fmt.Errorf("création arômes: %w", err)
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO tastings (
+				product_name, maker, city, score, notes, mode,
+				aroma_ids, latitude, longitude, photo_url
+			)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,'')
+			RETURNING id
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, row := range rows {
+			aromaIDs := make([]string, 0, len(row.aromaIDs))
+			for _, id := range row.aromaIDs {
+				aromaIDs = append(aromaIDs, strconv.Itoa(id))
+			}
+			for _, name := range row.newAromas {
+				aromaIDs = append(aromaIDs, strconv.Itoa(created[aromaKey(name)]))
+			}
+
+			var id string
+			if err := stmt.QueryRowContext(ctx,
+				row.productName, row.maker, row.city, row.score, row.notes, ModeQuick,
+				buildPgIntArray(aromaIDs), row.lat, row.lon,
+			).Scan(&id); err != nil {
+				return fmt.Errorf("ligne %d: %w", row.line, err)
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
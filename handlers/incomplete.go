@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ─── Fiches à compléter ────────────────────────────────────────────────────
+//
+// Saisies rapides restées en plan : sans photo, sans notes ou sans note.
+// Même page que la bibliothèque (index.html), restreinte à ces fiches.
+
+// Champs manquants filtrables (?missing=photo) -> condition SQL (liste blanche)
+var incompleteFields = []struct {
+	Param  string
+	Label  string
+	Clause string
+}{
+	// Photo en cours de traitement : pas encore d'URL, mais rien à compléter
+	{"photo", "Sans photo", `(COALESCE(photo_url,'') = '' AND COALESCE(photo_status,'') <> '` + PhotoPending + `')`},
+	{"notes", "Sans notes", `COALESCE(notes,'') = ''`},
+	{"score", "Sans note", `COALESCE(score,0) = 0`},
+}
+
+// incompleteAny : au moins un des champs manque
+var incompleteAny = func() string {
+	parts := make([]string, len(incompleteFields))
+	for i, f := range incompleteFields {
+		parts[i] = f.Clause
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}()
+
+// IncompleteView = filtre actif de la page /incomplete.
+type IncompleteView struct {
+	Missing string // "" : n'importe quel champ
+	Filters []IncompleteFilter
+
+	clause string
+}
+
+// IncompleteFilter = un lien de filtre par champ manquant.
+type IncompleteFilter struct {
+	Label  string
+	URL    string
+	Active bool
+}
+
+// newIncompleteView résout ?missing= (inconnu : tous les champs).
+func newIncompleteView(missing string) *IncompleteView {
+	v := &IncompleteView{clause: incompleteAny}
+	missing = strings.ToLower(strings.TrimSpace(missing))
+	for _, f := range incompleteFields {
+		if f.Param == missing {
+			v.Missing, v.clause = f.Param, f.Clause
+		}
+	}
+
+	v.Filters = append(v.Filters, IncompleteFilter{Label: "Tout", URL: URLFor("/incomplete"), Active: v.Missing == ""})
+	for _, f := range incompleteFields {
+		v.Filters = append(v.Filters, IncompleteFilter{
+			Label:  f.Label,
+			URL:    URLFor("/incomplete?missing=" + f.Param),
+			Active: v.Missing == f.Param,
+		})
+	}
+	return v
+}
+
+// Incomplete liste les fiches non archivées auxquelles il manque une photo, des notes ou une note.
+// GET /incomplete[?missing=photo|notes|score]
+func Incomplete(w http.ResponseWriter, r *http.Request) {
+	renderLibrary(w, r, http.StatusOK, "", nil, newIncompleteView(r.URL.Query().Get("missing")))
+}
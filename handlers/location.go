@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ─── Position d'une fiche (pin déplacé sur la carte) ───────────────────────
+
+// nominatimReverse = champs utiles d'un géocodage inverse Nominatim.
+type nominatimReverse struct {
+	Address struct {
+		City         string `json:"city"`
+		Town         string `json:"town"`
+		Village      string `json:"village"`
+		Municipality string `json:"municipality"`
+	} `json:"address"`
+}
+
+// reverseGeocodeCity renvoie la ville la plus proche de lat/lon ("" si aucune).
+func reverseGeocodeCity(ctx context.Context, lat, lon float64) (string, error) {
+	// 6 décimales (~10 cm) : un même point retombe sur la même entrée du cache
+	body, err := fetchNominatim(ctx, nominatimReverseURL(
+		strconv.FormatFloat(lat, 'f', 6, 64), strconv.FormatFloat(lon, 'f', 6, 64)))
+	if err != nil {
+		return "", err
+	}
+	var res nominatimReverse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	a := res.Address
+	for _, c := range []string{a.City, a.Town, a.Village, a.Municipality} {
+		if c = normalizeText(c); c != "" {
+			return c, nil
+		}
+	}
+	return "", nil
+}
+
+// parseCoordinate lit une coordonnée obligatoire dans [-limit, limit].
+func parseCoordinate(s string, limit float64) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || f < -limit || f > limit {
+		return 0, false
+	}
+	return f, true
+}
+
+// SetTastingLocation déplace une fiche (pin glissé sur la carte) et renvoie la
+// ville trouvée à la nouvelle position. set_city=1 : la ville de la fiche est
+// remplacée par celle-ci (si le géocodage en trouve une).
+// POST /api/v1/tastings/{id}/location  lat=45.76&lon=4.83[&set_city=1]
+func SetTastingLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "id manquant")
+		return
+	}
+
+	lat, okLat := parseCoordinate(r.FormValue("lat"), 90)
+	lon, okLon := parseCoordinate(r.FormValue("lon"), 180)
+	if !okLat || !okLon {
+		writeError(w, http.StatusBadRequest, "bad_request", errCoordsOutOfRange.Error())
+		return
+	}
+
+	// Ville d'abord : une panne Nominatim ne bloque pas le déplacement
+	geoCtx, geoCancel := context.WithTimeout(r.Context(), geoHTTPClient.Timeout)
+	city, err := reverseGeocodeCity(geoCtx, lat, lon)
+	geoCancel()
+	if err != nil {
+		log.Printf("Géocodage inverse ignoré: %.200s", err)
+	}
+
+	newCity := ""
+	if parseBoolParam(r.FormValue("set_city")) && city != "" {
+		newCity = truncateRunes(city, MaxCityLength)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var savedCity string
+	err = queryRowContext(ctx, "location.update", `
+		UPDATE tastings SET latitude = $1, longitude = $2, city = COALESCE(NULLIF($3, ''), city),
+			version = version + 1
+		WHERE id = $4
+		RETURNING COALESCE(city, '')
+	`, lat, lon, newCity, id).Scan(&savedCity)
+	if errors.Is(err, sql.ErrNoRows) || isInvalidID(err) {
+		writeError(w, http.StatusNotFound, "not_found", "dégustation introuvable")
+		return
+	}
+	if err != nil {
+		log.Println("Erreur déplacement fiche:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	publishTastingEvent("tasting.updated", id)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":            true,
+		"id":            id,
+		"lat":           lat,
+		"lng":           lon,
+		"city":          savedCity,
+		"geocoded_city": city,
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ─── Base indisponible (page de maintenance) ───────────────────────────────
+//
+// Une erreur 500 pendant une panne Supabase devient une 503 avec Retry-After :
+// message clair pour l'utilisateur, et les robots espacent leurs passages.
+// Les handlers n'ont pas à distinguer les cas : renderError vérifie la base.
+
+const (
+	maintenanceRetryAfter = 30 * time.Second
+	dbPingTimeout         = 2 * time.Second
+	// Résultat du ping réutilisé : pas un ping par requête en échec
+	dbPingTTL = 5 * time.Second
+)
+
+var dbHealth struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	down      bool
+}
+
+// dbReachable indique si la base répond (ping mis en cache dbPingTTL).
+func dbReachable(ctx context.Context) bool {
+	if DB == nil {
+		return true
+	}
+	dbHealth.mu.Lock()
+	defer dbHealth.mu.Unlock()
+	if time.Since(dbHealth.checkedAt) < dbPingTTL {
+		return !dbHealth.down
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dbPingTimeout)
+	defer cancel()
+	err := DB.PingContext(ctx)
+	if err != nil && !dbHealth.down {
+		log.Println("⚠️ Base injoignable:", err)
+	}
+	dbHealth.down = err != nil
+	dbHealth.checkedAt = time.Now()
+	return !dbHealth.down
+}
+
+// renderMaintenance répond 503 + Retry-After (maintenance.html, ou JSON pour l'API).
+func renderMaintenance(w http.ResponseWriter, r *http.Request) {
+	retry := int(maintenanceRetryAfter.Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	const message = "Le service est momentanément indisponible, réessaie dans un instant."
+
+	if wantsJSON(r) {
+		writeError(w, http.StatusServiceUnavailable, errorCode(http.StatusServiceUnavailable), message)
+		return
+	}
+
+	data := struct {
+		Message    string
+		RetryAfter int
+	}{message, retry}
+
+	var buf bytes.Buffer
+	if Tmpl == nil || Tmpl.ExecuteTemplate(&buf, "maintenance.html", data) != nil {
+		http.Error(w, message, http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+)
+
+// ─── Manifest PWA (personnalisable) ────────────────────────────────────────
+
+// static/manifest.json sert de modèle : nom et couleurs y sont remplacés par la
+// configuration (APP_NAME, APP_SHORT_NAME, THEME_COLOR, BACKGROUND_COLOR).
+const manifestTemplatePath = "static/manifest.json"
+
+// Valeurs par défaut (celles du modèle) ; écrasées par main si configurées.
+var (
+	AppName         = "Cacao — Journal de dégustation"
+	AppShortName    = "Cacao"
+	ThemeColor      = "#2C1810"
+	BackgroundColor = "#FBF6EF"
+)
+
+var cssHexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ValidColor : couleur hexadécimale CSS (#rgb, #rrggbb ou #rrggbbaa).
+func ValidColor(s string) bool { return cssHexColor.MatchString(s) }
+
+// Manifest sert le manifest PWA avec le nom et les couleurs configurés.
+// Les URLs relatives du modèle (écrites depuis /static/) sont rendues absolues (BASE_PATH inclus).
+// GET /manifest.json
+func Manifest(w http.ResponseWriter, r *http.Request) {
+	raw, err := os.ReadFile(manifestTemplatePath)
+	if err != nil {
+		log.Println("Erreur lecture manifest:", err)
+		http.Error(w, "manifest indisponible", http.StatusInternalServerError)
+		return
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		log.Println("Erreur manifest:", err)
+		http.Error(w, "manifest indisponible", http.StatusInternalServerError)
+		return
+	}
+
+	m["name"] = AppName
+	m["short_name"] = AppShortName
+	m["theme_color"] = ThemeColor
+	m["background_color"] = BackgroundColor
+
+	base, _ := url.Parse(URLFor("/static/manifest.json"))
+	resolve := func(v any) any {
+		s, ok := v.(string)
+		if !ok {
+			return v
+		}
+		ref, err := url.Parse(s)
+		if err != nil {
+			return v
+		}
+		return base.ResolveReference(ref).String()
+	}
+	for _, key := range []string{"start_url", "scope"} {
+		if v, ok := m[key]; ok {
+			m[key] = resolve(v)
+		}
+	}
+	for key, field := range map[string]string{"icons": "src", "shortcuts": "url"} {
+		items, _ := m[key].([]any)
+		for _, it := range items {
+			if obj, ok := it.(map[string]any); ok {
+				obj[field] = resolve(obj[field])
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		log.Println("Erreur encodage manifest:", err)
+	}
+}
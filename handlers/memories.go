@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ─── "Ce jour-là" ──────────────────────────────────────────────────────────
+
+// OnThisDay renvoie les dégustations faites le même jour/mois les années précédentes.
+// GET /api/v1/on-this-day
+func OnThisDay(w http.ResponseWriter, r *http.Request) {
+	loc := appLocation()
+	now := time.Now().In(loc)
+	startOfYear := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, loc)
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	tastings, err := queryTastings(ctx, "memories.tastings", `SELECT`+tastingSelectCols+`FROM tastings
+		WHERE EXTRACT(MONTH FROM created_at AT TIME ZONE $1) = $2
+		  AND EXTRACT(DAY FROM created_at AT TIME ZONE $1) = $3
+		  AND created_at < $4
+		ORDER BY created_at DESC`,
+		loc.String(), int(now.Month()), now.Day(), startOfYear,
+	)
+	if err != nil {
+		log.Println("Erreur on-this-day:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tastings)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ─── Normalisation des champs texte (produit / maker / ville) ──────────────
+
+// Caractères invisibles fréquents dans les copier-coller web
+var invisibleRunes = map[rune]bool{
+	'\u00ad': true, // trait d'union conditionnel
+	'\u200b': true, // espace de largeur nulle
+	'\u200c': true,
+	'\u200d': true,
+	'\u2060': true,
+	'\ufeff': true, // BOM
+}
+
+// normalizeText : suppression des caractères invisibles et des contrôles,
+// composition NFC ("e" + accent combinant = "é"), espaces (insécables compris)
+// fusionnés et rognés.
+func normalizeText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if invisibleRunes[r] || (unicode.IsControl(r) && !unicode.IsSpace(r)) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(s, ""))
+
+	return strings.Join(strings.Fields(norm.NFC.String(s)), " ")
+}
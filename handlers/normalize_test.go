@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"vide", "", ""},
+		{"inchangé", "Valrhona Guanaja", "Valrhona Guanaja"},
+		{"espace insécable", "Pralus\u00a0Tanzanie", "Pralus Tanzanie"},
+		{"espace fine insécable", "70\u202f%", "70 %"},
+		{"largeur nulle", "Ma\u200bker", "Maker"},
+		{"joiner et BOM", "\ufeffca\u200dcao\u2060", "cacao"},
+		{"trait d'union conditionnel", "choco\u00adlat", "chocolat"},
+		{"é décomposé", "Cafe\u0301", "Caf\u00e9"},
+		{"é déjà NFC", "Caf\u00e9", "Caf\u00e9"},
+		{"ç décomposé", "Franc\u0327ois", "Fran\u00e7ois"},
+		{"majuscule décomposée", "E\u0301cole", "\u00c9cole"},
+		{"signe isolé conservé", "\u0301a", "\u0301a"},
+		{"deux signes combinants", "Vie\u0323\u0302t Nam", "Vi\u1ec7t Nam"},
+		{"signe invisible intercalé", "Cafe\u200b\u0301", "Caf\u00e9"},
+		{"espaces fusionnés", "  Lyon \t\n  Croix-Rousse  ", "Lyon Croix-Rousse"},
+		{"contrôles supprimés", "Ly\x00on\x07", "Lyon"},
+		{"UTF-8 invalide", "Ly\xffon", "Lyon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeText(tt.in); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNewAromas(t *testing.T) {
+	got := parseNewAromas("noisette,\u00a0tabac ; cuir\u200b| ,re\u0301glisse")
+	want := []string{"noisette", "tabac", "cuir", "r\u00e9glisse"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseNewAromas = %q, want %q", got, want)
+	}
+}
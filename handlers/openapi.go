@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// ─── Contrat OpenAPI ───────────────────────────────────────────────────────
+
+// Spec écrite à la main : à mettre à jour avec chaque endpoint JSON.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]any
+)
+
+// OpenAPI sert le document OpenAPI 3, avec "servers" aligné sur BASE_PATH.
+// GET /api/v1/openapi.json
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		if err := json.Unmarshal(openAPISpec, &openAPIDoc); err != nil {
+			log.Println("Erreur parse openapi.json:", err)
+			return
+		}
+		openAPIDoc["servers"] = []map[string]string{{"url": URLFor("/")}}
+	})
+	if openAPIDoc == nil {
+		writeError(w, http.StatusInternalServerError, "internal", "spec indisponible")
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeJSON(w, http.StatusOK, openAPIDoc)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ─── Liste paginée (API JSON) ──────────────────────────────────────────────
+
+const (
+	defaultAPIPerPage = 20
+	maxAPIPerPage     = 100
+)
+
+// setPaginationHeaders expose la pagination en en-têtes (corps inchangé) :
+// X-Total-Count, X-Page, X-Per-Page et Link rel="prev"/"next" (RFC 5988).
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, p Pager) {
+	h := w.Header()
+	h.Set("X-Total-Count", strconv.Itoa(p.Total))
+	h.Set("X-Page", strconv.Itoa(p.Page))
+	h.Set("X-Per-Page", strconv.Itoa(p.PerPage))
+
+	// Add : l'alias /api peut déjà avoir posé un Link rel="successor-version"
+	if p.Page > 1 {
+		h.Add("Link", "<"+pageLink(r, p.Page-1)+`>; rel="prev"`)
+	}
+	if p.Page < p.TotalPages {
+		h.Add("Link", "<"+pageLink(r, p.Page+1)+`>; rel="next"`)
+	}
+}
+
+// pageLink reprend l'URL de la requête (mêmes filtres) en changeant seulement la page.
+func pageLink(r *http.Request, page int) string {
+	v := r.URL.Query()
+	v.Set("page", strconv.Itoa(page))
+	return URLFor(r.URL.Path + "?" + v.Encode())
+}
+
+// TastingList renvoie les dégustations (plus récentes d'abord), paginées.
+// Filtres : q (produit, chocolatier, ville), mode, archived=1 (archives incluses).
+// GET /api/v1/tastings?q=...&mode=deep&page=2&per_page=50
+func TastingList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page := parsePositiveInt(query.Get("page"), 1)
+	perPage := min(parsePositiveInt(query.Get("per_page"), defaultAPIPerPage), maxAPIPerPage)
+
+	where := statsArchiveClause(r)
+	var args []any
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		args = append(args, "%"+escapeLike(q)+"%")
+		n := len(args)
+		where += fmt.Sprintf(` AND (f_unaccent(product_name) ILIKE f_unaccent($%d) ESCAPE '\'
+			OR f_unaccent(COALESCE(maker,'')) ILIKE f_unaccent($%d) ESCAPE '\'
+			OR f_unaccent(COALESCE(city,'')) ILIKE f_unaccent($%d) ESCAPE '\')`, n, n, n)
+	}
+	if mode := strings.TrimSpace(query.Get("mode")); mode != "" {
+		if validateMode(mode) != strings.ToLower(mode) {
+			writeError(w, http.StatusBadRequest, "bad_request", "mode invalide")
+			return
+		}
+		args = append(args, strings.ToLower(mode))
+		where += fmt.Sprintf(` AND mode = $%d`, len(args))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var total int
+	if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tastings WHERE `+where, args...).Scan(&total); err != nil {
+		log.Println("Erreur compte liste API:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	// Page au-delà de la fin : liste vide (pas de rabattement, le client voit X-Total-Count)
+	totalPages := max(1, (total+perPage-1)/perPage)
+
+	args = append(args, perPage, (page-1)*perPage)
+	tastings, err := queryTastings(ctx, "api.tastings", `SELECT`+tastingSelectCols+`FROM tastings
+		WHERE `+where+fmt.Sprintf(`
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		log.Println("Erreur liste API:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	if tastings == nil {
+		tastings = []Tasting{}
+	}
+
+	setPaginationHeaders(w, r, Pager{Page: page, PerPage: perPage, TotalPages: totalPages, Total: total})
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "tastings": tastings})
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ─── Modification partielle (PATCH JSON) ───────────────────────────────────
+//
+// Seules les clés présentes dans le corps sont modifiées ; les autres colonnes
+// restent intactes (pas de formulaire complet à renvoyer).
+//
+// Sauvegardes rapides (curseur de note, notes de la fiche) :
+//   - If-Match: "<version>" : refus 412 si la fiche a changé depuis (concurrence optimiste) ;
+//   - Prefer: return=minimal : réponse réduite à id + version + champs modifiés.
+// La version courante est renvoyée en ETag.
+
+// Taille max du corps JSON d'un PATCH
+const maxPatchBody = 64 << 10
+
+// Longueur max d'une qualité du mode approfondi (champ libre du formulaire)
+const maxQualityLength = 100
+
+// patchField : colonne modifiable + conversion/validation de la valeur JSON.
+type patchField struct {
+	column string
+	parse  func(json.RawMessage) (any, error)
+}
+
+// Qualités du mode approfondi : toujours vides sur une fiche en mode rapide
+var qualityColumns = []string{"vue_quality", "snap_quality", "melt_quality", "finish_length"}
+
+// parseIfMatch lit la version attendue (If-Match: "3", W/"3" ou 3). 0 : pas de condition.
+func parseIfMatch(r *http.Request) (int, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(h, "W/"), `"`))
+	if err != nil || v <= 0 {
+		return 0, errors.New(`If-Match invalide (version attendue, ex : "3")`)
+	}
+	return v, nil
+}
+
+func versionETag(v int) string {
+	return `"` + strconv.Itoa(v) + `"`
+}
+
+func patchText(label string, max int) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("%s : texte attendu", label)
+		}
+		return validateField(label, s, max)
+	}
+}
+
+func patchCoord(label string, limit float64) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		var f *float64
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("%s : nombre ou null attendu", label)
+		}
+		if f != nil && (math.IsNaN(*f) || *f < -limit || *f > limit) {
+			return nil, errCoordsOutOfRange
+		}
+		return f, nil
+	}
+}
+
+// Champs autorisés (jamais de nom de colonne venant du client)
+var patchFields = map[string]patchField{
+	"product_name": {"product_name", func(raw json.RawMessage) (any, error) {
+		v, err := patchText("Nom du produit", MaxProductNameLength)(raw)
+		if err == nil && v == "" {
+			err = errors.New("Nom du produit obligatoire")
+		}
+		return v, err
+	}},
+	"maker": {"maker", patchText("Chocolatier", MaxMakerLength)},
+	"city":  {"city", patchText("Ville", MaxCityLength)},
+	"notes": {"notes", func(raw json.RawMessage) (any, error) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, errors.New("notes : texte attendu")
+		}
+		return sanitizeText(s, MaxNotesLength), nil
+	}},
+	"score": {"score", func(raw json.RawMessage) (any, error) {
+		var f float64
+		if err := json.Unmarshal(raw, &f); err != nil || f < 0 || f > 10 {
+			return nil, errors.New("score : nombre entre 0 et 10 attendu")
+		}
+		return math.Round(f*10) / 10, nil
+	}},
+	"mode": {"mode", func(raw json.RawMessage) (any, error) {
+		var s string
+		_ = json.Unmarshal(raw, &s)
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !slices.Contains(allowedModes, s) {
+			return nil, fmt.Errorf("mode inconnu (valeurs possibles : %s)", strings.Join(allowedModes, ", "))
+		}
+		return s, nil
+	}},
+	"aroma_ids": {"aroma_ids", func(raw json.RawMessage) (any, error) {
+		var ids []int
+		if err := json.Unmarshal(raw, &ids); err != nil {
+			return nil, errors.New("aroma_ids : liste d'entiers attendue")
+		}
+		strs := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if id > 0 {
+				strs = append(strs, strconv.Itoa(id))
+			}
+		}
+		return buildPgIntArray(strs), nil
+	}},
+	"latitude":      {"latitude", patchCoord("latitude", 90)},
+	"longitude":     {"longitude", patchCoord("longitude", 180)},
+	"vue_quality":   {"vue_quality", patchText("Vue", maxQualityLength)},
+	"snap_quality":  {"snap_quality", patchText("Cassant", maxQualityLength)},
+	"melt_quality":  {"melt_quality", patchText("Texture", maxQualityLength)},
+	"finish_length": {"finish_length", patchText("Finale", maxQualityLength)},
+}
+
+// PatchTasting modifie uniquement les champs fournis d'une dégustation et renvoie la fiche.
+// PATCH /api/v1/tastings/{id}  {"score": 8.5, "notes": "…"}
+func PatchTasting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		w.Header().Set("Allow", http.MethodPatch)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "id manquant")
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPatchBody)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "corps JSON invalide (objet attendu)")
+		return
+	}
+	if len(body) == 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "aucun champ à modifier")
+		return
+	}
+	_, hasLat := body["latitude"]
+	_, hasLon := body["longitude"]
+	if hasLat != hasLon {
+		writeError(w, http.StatusBadRequest, "bad_request", "latitude et longitude se modifient ensemble")
+		return
+	}
+
+	// Ordre stable des colonnes : même requête pour un même jeu de champs
+	keys := make([]string, 0, len(body))
+	for k := range body {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	// Mode demandé ("" : inchangé)
+	mode := ""
+	if raw, ok := body["mode"]; ok {
+		v, err := patchFields["mode"].parse(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		mode = v.(string)
+	}
+
+	var sets []string
+	var args []any
+	hasQuality := false
+	for _, k := range keys {
+		field, ok := patchFields[k]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "bad_request", "champ non modifiable : "+k)
+			return
+		}
+		v, err := field.parse(body[k])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		args = append(args, v)
+		switch {
+		case !slices.Contains(qualityColumns, field.column) || mode == ModeDeep:
+			sets = append(sets, fmt.Sprintf("%s = $%d", field.column, len(args)))
+		case mode == "":
+			// Mode inchangé : la qualité n'est gardée que sur une fiche approfondie
+			sets = append(sets, fmt.Sprintf("%s = CASE WHEN mode = '%s' THEN $%d ELSE '' END", field.column, ModeDeep, len(args)))
+		default:
+			hasQuality = true
+		}
+	}
+	if hasQuality {
+		writeError(w, http.StatusBadRequest, "bad_request", "qualités réservées au mode approfondi")
+		return
+	}
+
+	// Passage en mode rapide : on vide les qualités, comme à l'ajout
+	if mode != "" && mode != ModeDeep {
+		sets = append(sets, `vue_quality = '', snap_quality = '', melt_quality = '', finish_length = ''`)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	args = append(args, id)
+	where := fmt.Sprintf(` WHERE id = $%d`, len(args))
+	if ifMatch > 0 {
+		args = append(args, ifMatch)
+		where += fmt.Sprintf(` AND version = $%d`, len(args))
+	}
+	// Version incrémentée ici, pas par trigger : les écritures système (photo, archivage…)
+	// ne font pas échouer une sauvegarde rapide en cours
+	sets = append(sets, `version = version + 1`)
+	row := DB.QueryRowContext(ctx, `UPDATE tastings SET `+strings.Join(sets, ", ")+where+` RETURNING`+tastingSelectCols, args...)
+	t, err := scanTasting(row, aromaNameMap())
+	if errors.Is(err, sql.ErrNoRows) || isInvalidID(err) {
+		patchNoRow(ctx, w, id, ifMatch)
+		return
+	}
+	if err != nil {
+		log.Println("Erreur modification partielle:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	publishTastingEvent("tasting.updated", t.ID)
+	w.Header().Set("ETag", versionETag(t.Version))
+	if strings.Contains(r.Header.Get("Prefer"), "return=minimal") {
+		w.Header().Set("Preference-Applied", "return=minimal")
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": t.ID, "version": t.Version, "updated": keys})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "updated": keys, "tasting": t})
+}
+
+// patchNoRow distingue fiche absente (404) et version dépassée (412, version courante en ETag).
+func patchNoRow(ctx context.Context, w http.ResponseWriter, id string, ifMatch int) {
+	var current int
+	err := DB.QueryRowContext(ctx, `SELECT version FROM tastings WHERE id = $1`, id).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), isInvalidID(err):
+		writeError(w, http.StatusNotFound, "not_found", "dégustation introuvable")
+	case err != nil:
+		log.Println("Erreur lecture version:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+	default:
+		w.Header().Set("ETag", versionETag(current))
+		writeError(w, http.StatusPreconditionFailed, "version_conflict",
+			fmt.Sprintf("fiche modifiée entre-temps (version %d, attendue %d) : recharge-la", current, ifMatch))
+	}
+}
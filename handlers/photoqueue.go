@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ─── Traitement des photos en arrière-plan ─────────────────────────────────
+//
+// La fiche est enregistrée tout de suite avec photo_status = pending ; un pool
+// de workers redimensionne et envoie la photo, puis passe la fiche à done/failed.
+
+// États de la photo d'une fiche (colonne photo_status, "" : pas de photo)
+const (
+	PhotoPending = "pending"
+	PhotoDone    = "done"
+	PhotoFailed  = "failed"
+)
+
+const (
+	DefaultPhotoWorkers = 2
+	photoQueueSize      = 16
+	photoJobTimeout     = 2 * time.Minute // décodage + variantes + uploads avec retries
+	photoQueueWait      = 3 * time.Second // attente max d'une place en file (dans la requête)
+)
+
+type photoJob struct {
+	TastingID string
+	Data      []byte
+	RequestID string // requête d'origine (logs)
+}
+
+// photoQueue = nil : workers non démarrés (sous-commandes), traitement hors file
+var photoQueue chan photoJob
+
+// StartPhotoWorkers lance n workers jusqu'à l'arrêt de ctx.
+// La file est en mémoire : les photos restées "pending" d'un run précédent sont perdues.
+func StartPhotoWorkers(ctx context.Context, n int) {
+	failStalePhotos(ctx)
+
+	q := make(chan photoJob, photoQueueSize)
+	for range max(n, 1) {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-q:
+					processPhotoJob(job)
+				}
+			}
+		}()
+	}
+	photoQueue = q
+}
+
+// queuePhoto confie la photo aux workers sans bloquer la réponse au-delà de
+// photoQueueWait : file encore pleine passé ce délai, la photo est abandonnée
+// (fiche en failed, à renvoyer). Sans workers : traitement dans une goroutine.
+func queuePhoto(r *http.Request, job photoJob) {
+	job.RequestID = RequestIDFrom(r.Context())
+	if photoQueue == nil {
+		go processPhotoJob(job)
+		return
+	}
+
+	timer := time.NewTimer(photoQueueWait)
+	defer timer.Stop()
+	select {
+	case photoQueue <- job:
+		return
+	case <-timer.C:
+	}
+
+	log.Printf("File photo pleine, photo abandonnée%s: fiche %s", requestTag(r.Context()), job.TastingID)
+	ctx, cancel := detachContext(r, dbTimeout)
+	defer cancel()
+	if err := setPhotoStatus(ctx, job.TastingID, PhotoFailed); err != nil {
+		log.Println("Erreur update photo_status:", err)
+	}
+	publishTastingEvent("tasting.updated", job.TastingID)
+}
+
+// processPhotoJob traite une photo avec un contexte détaché : la requête
+// qui l'a déposée est terminée depuis longtemps. Le délai court à partir du
+// début du traitement, pas de la mise en file.
+func processPhotoJob(job photoJob) {
+	ctx, cancel := detach(withRequestID(context.Background(), job.RequestID), photoJobTimeout)
+	defer cancel()
+
+	photo, err := processAndUploadImage(ctx, bytes.NewReader(job.Data), int64(len(job.Data)), job.TastingID)
+	if err == nil {
+		err = savePhoto(ctx, job.TastingID, photo)
+	}
+	if err != nil {
+		log.Printf("Erreur traitement photo%s: %v", requestTag(ctx), err)
+		if err := setPhotoStatus(ctx, job.TastingID, PhotoFailed); err != nil {
+			log.Println("Erreur update photo_status:", err)
+		}
+	}
+	publishTastingEvent("tasting.updated", job.TastingID)
+}
+
+// readPhotoUpload lit la photo du formulaire en mémoire (le fichier temporaire
+// multipart disparaît avec la requête). nil sans photo.
+func readPhotoUpload(r *http.Request) ([]byte, error) {
+	file, header, err := r.FormFile("photo")
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+
+	if header.Size > MaxUploadSize {
+		return nil, fmt.Errorf("fichier trop volumineux (max %d Mo)", MaxUploadSize>>20)
+	}
+	return io.ReadAll(io.LimitReader(file, MaxUploadSize))
+}
+
+func setPhotoStatus(ctx context.Context, tastingID, status string) error {
+	_, err := DB.ExecContext(ctx, `UPDATE tastings SET photo_status=$1 WHERE id=$2`, status, tastingID)
+	return err
+}
+
+// failStalePhotos marque en échec les photos restées en file lors du dernier arrêt.
+func failStalePhotos(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	res, err := DB.ExecContext(ctx, `UPDATE tastings SET photo_status=$1 WHERE photo_status=$2`, PhotoFailed, PhotoPending)
+	if err != nil {
+		log.Println("Erreur reprise photos en attente:", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("⚠️ %d photo(s) en attente perdue(s) à l'arrêt précédent", n)
+	}
+}
+
+// PhotoStatus indique où en est la photo d'une fiche (polling après ajout).
+// GET /api/v1/tastings/{id}/photo-status
+func PhotoStatus(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "id manquant")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var status, photoURL, variantsRaw string
+	err := DB.QueryRowContext(ctx, `
+		SELECT photo_status, COALESCE(photo_url,''), COALESCE(photo_variants::text,'{}')
+		FROM tastings WHERE id = $1
+	`, id).Scan(&status, &photoURL, &variantsRaw)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not_found", "dégustation introuvable")
+		return
+	}
+	if err != nil {
+		log.Println("Erreur photo_status:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	// Tant que la photo est en file, le client repasse dans quelques secondes
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":             true,
+		"id":             id,
+		"status":         status,
+		"photo_url":      photoURL,
+		"photo_variants": parsePhotoVariants(variantsRaw),
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ─── Rate limiting (routes d'écriture) ─────────────────────────────────────
+
+// Valeurs par défaut : 30 écritures/minute par IP, rafale de 10.
+const (
+	DefaultWriteRatePerMinute = 30
+	DefaultWriteBurst         = 10
+)
+
+// tokenBucket : seau à jetons, rechargé en continu à `rate` jetons/seconde.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // jetons par seconde
+	burst   float64
+	buckets map[string]*tokenBucket // seaux inactifs purgés par le planificateur
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow consomme un jeton pour key ; sinon renvoie le délai avant le prochain jeton.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup oublie les seaux pleins (client inactif depuis assez longtemps).
+func (l *rateLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// writeLimiter = nil : rate limiting désactivé
+var writeLimiter = newRateLimiter(DefaultWriteRatePerMinute, DefaultWriteBurst)
+
+// SetWriteRateLimit configure la limite des routes d'écriture (perMinute <= 0 : désactivé).
+func SetWriteRateLimit(perMinute, burst int) {
+	if perMinute <= 0 {
+		writeLimiter = nil
+		return
+	}
+	writeLimiter = newRateLimiter(perMinute, burst)
+}
+
+// RateLimit limite les requêtes d'écriture par IP (429 + Retry-After).
+// Les lectures (GET/HEAD) ne sont jamais limitées.
+func RateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || allowWrite(w, r) {
+			next(w, r)
+		}
+	}
+}
+
+// allowWrite consomme un jeton pour le client ; sinon répond 429 et renvoie false.
+func allowWrite(w http.ResponseWriter, r *http.Request) bool {
+	limiter := writeLimiter
+	if limiter == nil {
+		return true
+	}
+	if ok, wait := limiter.allow(ClientIP(r)); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		renderError(w, r, http.StatusTooManyRequests, "Trop de requêtes, réessaie dans quelques secondes.")
+		return false
+	}
+	return true
+}
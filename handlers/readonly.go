@@ -0,0 +1,31 @@
+package handlers
+
+import "net/http"
+
+// ─── Mode lecture seule (démo) ─────────────────────────────────────────────
+
+// ReadOnly (READ_ONLY=1, défini par main) : consultation uniquement, aucune écriture.
+var ReadOnly bool
+
+// POST sans effet sur les données : toujours autorisés en mode démo.
+var readOnlySafePosts = map[string]bool{
+	"/api/route":         true,
+	"/api/score/suggest": true,
+}
+
+// ReadOnlyGuard bloque toutes les requêtes d'écriture quand ReadOnly est actif.
+// Chemins vus sans BASE_PATH (à monter sous le StripPrefix).
+func ReadOnlyGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ReadOnly && !readOnlySafePosts[apiRoutePath(r.URL.Path)] {
+			renderError(w, r, http.StatusForbidden, "Mode démo : l'application est en lecture seule.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
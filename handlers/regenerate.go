@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ─── Régénération des photos (variantes, blurhash, couleur) ────────────────
+//
+// Les fiches antérieures aux variantes srcset / blurhash / couleur moyenne n'ont
+// que la photo d'origine. On la retélécharge, on la repasse dans le pipeline
+// d'upload, puis on remplace l'URL seulement si la photo n'a pas changé entre-temps.
+// Reprise possible à tout moment : seules les fiches incomplètes sont sélectionnées.
+
+const (
+	DefaultRegenBatch = 20
+	regenDelay        = 500 * time.Millisecond // entre deux photos : ménage le storage
+)
+
+// RegenStats résume un passage de régénération.
+type RegenStats struct {
+	Done      int
+	Failed    int
+	Remaining int // fiches encore incomplètes après ce passage (échecs compris)
+}
+
+// Fiches avec photo mais sans variantes ni métadonnées (hors traitement en cours)
+const incompletePhotoClause = `COALESCE(photo_url,'') <> ''
+	AND COALESCE(photo_status,'') <> '` + PhotoPending + `'
+	AND (photo_variants IS NULL OR photo_variants = '{}'::jsonb
+		OR COALESCE(blur_hash,'') = '' OR COALESCE(photo_color,'') = '')`
+
+// RegenerateMissingPhotos traite au plus limit fiches incomplètes (limit <= 0 : toutes).
+func RegenerateMissingPhotos(ctx context.Context, limit int) (RegenStats, error) {
+	var st RegenStats
+
+	query := `SELECT id, photo_url FROM tastings WHERE ` + incompletePhotoClause + ` ORDER BY created_at`
+	args := []any{}
+	if limit > 0 {
+		query += ` LIMIT $1`
+		args = append(args, limit)
+	}
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return st, err
+	}
+	type candidate struct{ id, photoURL string }
+	var todo []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.photoURL); err != nil {
+			rows.Close()
+			return st, err
+		}
+		todo = append(todo, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+
+	for i, c := range todo {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return st, ctx.Err()
+			case <-time.After(regenDelay):
+			}
+		}
+		if err := regeneratePhoto(ctx, c.id, c.photoURL); err != nil {
+			st.Failed++
+			log.Printf("Photos %d/%d : échec fiche %s: %v", i+1, len(todo), c.id, err)
+			continue
+		}
+		st.Done++
+		log.Printf("Photos %d/%d : fiche %s régénérée", i+1, len(todo), c.id)
+	}
+
+	err = DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tastings WHERE `+incompletePhotoClause).Scan(&st.Remaining)
+	return st, err
+}
+
+func regeneratePhoto(ctx context.Context, tastingID, photoURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, photoJobTimeout)
+	defer cancel()
+
+	data, err := downloadPhoto(ctx, photoURL)
+	if err != nil {
+		return fmt.Errorf("téléchargement: %w", err)
+	}
+	photo, err := processAndUploadImage(ctx, bytes.NewReader(data), int64(len(data)), tastingID)
+	if err != nil {
+		return err
+	}
+	uploaded := slices.Collect(maps.Values(photo.Variants))
+
+	variants, err := json.Marshal(photo.Variants)
+	if err != nil {
+		return err
+	}
+	// photo_url inchangée : la photo n'a pas été remplacée pendant le traitement
+	res, err := DB.ExecContext(ctx, `
+		UPDATE tastings SET photo_url=$1, blur_hash=$2, photo_color=$3, photo_variants=$4, photo_status=$5
+		WHERE id=$6 AND photo_url=$7
+	`, photo.URL, photo.BlurHash, photo.Color, string(variants), PhotoDone, tastingID, photoURL)
+	if err != nil {
+		deletePhotoAsync(ctx, uploaded...)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		deletePhotoAsync(ctx, uploaded...)
+		return fmt.Errorf("photo modifiée ou fiche supprimée pendant le traitement")
+	}
+
+	// L'original n'est supprimé qu'une fois la fiche pointant vers la nouvelle version
+	if !slices.Contains(uploaded, photoURL) {
+		deletePhotoAsync(ctx, photoURL)
+	}
+	publishTastingEvent("tasting.updated", tastingID)
+	return nil
+}
+
+// downloadPhoto récupère une photo publiée (bucket public), plafonnée à MaxUploadSize.
+func downloadPhoto(ctx context.Context, photoURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, photoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := uploadHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpError{Status: resp.Status}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadSize {
+		return nil, fmt.Errorf("photo trop volumineuse (max %d Mo)", MaxUploadSize>>20)
+	}
+	return data, nil
+}
+
+// RegeneratePhotos régénère un lot de photos incomplètes (à rappeler tant que remaining > 0).
+// POST /admin/photos/regenerate[?limit=20]
+func RegeneratePhotos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	limit := min(parsePositiveInt(r.URL.Query().Get("limit"), DefaultRegenBatch), 100)
+
+	// Lot long : on repousse le WriteTimeout global du serveur
+	const batchTimeout = 10 * time.Minute
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(batchTimeout))
+
+	// Détaché de la requête : un client qui coupe n'interrompt pas une photo à mi-chemin
+	ctx, cancel := detachContext(r, batchTimeout)
+	defer cancel()
+
+	st, err := RegenerateMissingPhotos(ctx, limit)
+	if err != nil {
+		log.Println("Erreur régénération photos:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	log.Printf("Photos régénérées : %d (échecs %d, restantes %d)", st.Done, st.Failed, st.Remaining)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "done": st.Done, "failed": st.Failed, "remaining": st.Remaining})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// ─── Identifiant de requête et contextes détachés ──────────────────────────
+//
+// Le contexte d'une requête est annulé dès la réponse envoyée : un travail
+// lancé en arrière-plan depuis un handler (photo, suppression storage…) ne doit
+// jamais l'utiliser, sous peine de "context canceled" au milieu d'un upload.
+// Règle : toute goroutine lancée depuis un handler part de detachContext(r, d),
+// qui ne garde de la requête que l'identifiant (pour relier les logs) et borne
+// le travail à d.
+
+type requestIDKey struct{}
+
+// X-Request-Id accepté tel quel (posé par le reverse proxy) s'il reste raisonnable
+var requestIDRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID attribue un identifiant à chaque requête (X-Request-Id reçu ou
+// généré), le place dans le contexte et le renvoie en en-tête de réponse.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !requestIDRe.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(withRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom renvoie l'identifiant de requête de ctx ("" hors requête).
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestTag : suffixe de log " [req <id>]" ("" hors requête).
+func requestTag(ctx context.Context) string {
+	if id := RequestIDFrom(ctx); id != "" {
+		return " [req " + id + "]"
+	}
+	return ""
+}
+
+// detach renvoie un contexte issu de context.Background() (jamais annulé par
+// ctx), portant l'identifiant de requête de ctx et limité à timeout.
+func detach(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(withRequestID(context.Background(), RequestIDFrom(ctx)), timeout)
+}
+
+// detachContext : contexte pour un travail qui survit à la requête r.
+func detachContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return detach(r.Context(), timeout)
+}
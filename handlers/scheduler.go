@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ─── Tâches de maintenance périodiques ─────────────────────────────────────
+
+// DefaultCleanupInterval : fréquence par défaut (CLEANUP_INTERVAL, ex: "10m").
+const DefaultCleanupInterval = 5 * time.Minute
+
+type cleanupTask struct {
+	name string
+	fn   func(ctx context.Context)
+}
+
+var scheduler struct {
+	mu    sync.Mutex
+	tasks []cleanupTask
+}
+
+// RegisterCleanup ajoute une tâche exécutée à chaque tick du planificateur.
+func RegisterCleanup(name string, fn func(ctx context.Context)) {
+	scheduler.mu.Lock()
+	scheduler.tasks = append(scheduler.tasks, cleanupTask{name: name, fn: fn})
+	scheduler.mu.Unlock()
+}
+
+// Tâches intégrées (caches mémoire)
+func init() {
+	RegisterCleanup("geo-cache", func(context.Context) { geoCache_.cleanupExpired() })
+	RegisterCleanup("product-suggest-cache", func(context.Context) { productSuggestCache.cleanupExpired() })
+	RegisterCleanup("barcode-cache", func(context.Context) { barcodeCache.cleanupExpired() })
+	RegisterCleanup("rate-limit", func(context.Context) {
+		if l := writeLimiter; l != nil {
+			l.cleanup()
+		}
+	})
+}
+
+// RunScheduler exécute les tâches toutes les `interval` jusqu'à l'annulation de ctx.
+// Une tâche qui panique est loggée sans arrêter les autres.
+func RunScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCleanupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scheduler.mu.Lock()
+			tasks := append([]cleanupTask(nil), scheduler.tasks...)
+			scheduler.mu.Unlock()
+
+			for _, t := range tasks {
+				runCleanupTask(ctx, t, interval)
+			}
+		}
+	}
+}
+
+func runCleanupTask(ctx context.Context, t cleanupTask, timeout time.Duration) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Erreur tâche %s: %v", t.name, rec)
+		}
+	}()
+
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	t.fn(tctx)
+}
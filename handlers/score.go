@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// ─── Score suggéré (mode approfondi) ───────────────────────────────────────
+
+// Poids (sur 10) associés aux qualités du mode approfondi.
+// Les valeurs inconnues sont ignorées : le score reste indicatif.
+var qualityWeights = map[string]float64{
+	// génériques
+	"excellent": 10, "excellente": 10,
+	"très bon": 8.5, "très bonne": 8.5,
+	"bon": 7, "bonne": 7,
+	"moyen": 5, "moyenne": 5,
+	"médiocre": 3,
+	"mauvais":  1.5, "mauvaise": 1.5,
+
+	// vue
+	"brillante": 9, "pleine": 7.5, "marbrée": 4, "mate": 5,
+
+	// cassant
+	"net": 9, "sec": 7.5, "friable": 4.5, "mou": 3,
+
+	// texture
+	"soyeuse": 9, "fondante": 8.5, "pâteuse": 4.5, "granuleuse": 3.5,
+
+	// longueur en bouche
+	"longue": 9, "courte": 4,
+}
+
+// qualityScore renvoie la moyenne des poids reconnus pour une valeur
+// éventuellement multiple ("Net, Sec").
+func qualityScore(v string) (float64, bool) {
+	var sum float64
+	var n int
+	for _, part := range strings.Split(v, ",") {
+		if w, ok := qualityWeights[strings.ToLower(strings.TrimSpace(part))]; ok {
+			sum += w
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// computeScore dérive un score indicatif (0-10, au dixième) des qualités.
+// ok=false si aucune qualité n'est exploitable.
+func computeScore(vue, snap, melt, finish string) (score float64, ok bool) {
+	var sum float64
+	var n int
+	for _, v := range []string{vue, snap, melt, finish} {
+		if s, found := qualityScore(v); found {
+			sum += s
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return math.Round(sum/float64(n)*10) / 10, true
+}
+
+// SuggestScore renvoie un score suggéré à partir des qualités (indicatif, non imposé).
+// POST /api/v1/score/suggest  vue_quality=Brillante&snap_quality=Net&melt_quality=Fondante&finish_length=Longue
+func SuggestScore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	score, ok := computeScore(
+		r.FormValue("vue_quality"),
+		r.FormValue("snap_quality"),
+		r.FormValue("melt_quality"),
+		r.FormValue("finish_length"),
+	)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "score": nil})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "score": score})
+}
+
+// ─── Moyennes (simple et pondérée par mode) ────────────────────────────────
+
+// Poids du mode dans la moyenne pondérée (SCORE_WEIGHT_QUICK / SCORE_WEIGHT_DEEP).
+// 1 et 1 par défaut : la moyenne pondérée vaut alors la moyenne simple.
+var (
+	ScoreWeightQuick = 1.0
+	ScoreWeightDeep  = 1.0
+)
+
+// ScoreAverages : moyennes formatées pour l'affichage ("" si aucune fiche notée).
+type ScoreAverages struct {
+	Simple   string
+	Weighted string
+}
+
+// scoreAverages calcule les deux moyennes sur les fiches notées vérifiant where
+// (condition SQL sur tastings, avec ses paramètres args).
+func scoreAverages(ctx context.Context, where string, args ...any) (ScoreAverages, error) {
+	var out ScoreAverages
+	if where == "" {
+		where = "TRUE"
+	}
+
+	n := len(args)
+	args = append(args, ScoreWeightDeep, ScoreWeightQuick)
+
+	var simple, weighted sql.NullFloat64
+	err := queryRowContext(ctx, "score.averages", fmt.Sprintf(`
+		SELECT AVG(score), SUM(score * w) / NULLIF(SUM(w), 0)
+		FROM (
+			SELECT score, CASE WHEN mode = 'deep' THEN $%d::float8 ELSE $%d::float8 END AS w
+			FROM tastings
+			WHERE score > 0 AND (%s)
+		) s
+	`, n+1, n+2, where), args...).Scan(&simple, &weighted)
+	if err != nil {
+		return out, err
+	}
+
+	if simple.Valid {
+		out.Simple = DisplayScore(math.Round(simple.Float64*10) / 10)
+	}
+	if weighted.Valid {
+		out.Weighted = DisplayScore(math.Round(weighted.Float64*10) / 10)
+	}
+	return out, nil
+}
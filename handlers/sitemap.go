@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ─── Sitemap (démo publique) ───────────────────────────────────────────────
+//
+// Il n'y a pas de partage fiche par fiche (ni jeton public) : seule une instance
+// en lecture seule (READ_ONLY, démo publique) expose ses fiches aux moteurs de
+// recherche. Ailleurs, le journal est privé et /sitemap.xml répond 404.
+
+// Limite du protocole sitemaps.org pour un seul fichier
+const maxSitemapURLs = 50000
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// AppURL : origine publique de l'instance (APP_URL, ex : https://cacao.example.org).
+// Vide : origine reconstituée depuis la requête, sitemap alors non partageable en cache.
+var AppURL string
+
+// SetAppURL lit APP_URL : schéma http(s) + hôte, sans chemin (BASE_PATH s'y ajoute).
+// En cas d'erreur, la valeur courante est conservée.
+func SetAppURL(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		AppURL = ""
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("APP_URL invalide : %q (attendu : https://hote[:port], sans chemin)", s)
+	}
+	AppURL = u.Scheme + "://" + u.Host
+	return nil
+}
+
+// requestOrigin reconstitue schéma + hôte de la requête (X-Forwarded-Proto derrière un proxy de confiance).
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || (TrustProxy && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// Sitemap liste l'accueil et les fiches non archivées d'une démo publique.
+// GET /sitemap.xml
+func Sitemap(w http.ResponseWriter, r *http.Request) {
+	if !ReadOnly {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `SELECT id, updated_at FROM tastings
+		WHERE `+notArchived+` ORDER BY created_at DESC LIMIT $1`, maxSitemapURLs-1)
+	if err != nil {
+		log.Println("Erreur sitemap:", err)
+		http.Error(w, "sitemap indisponible", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Hôte fourni par le client : réponse propre à la requête, jamais en cache partagé
+	origin, cacheControl := AppURL, "public, max-age=3600"
+	if origin == "" {
+		origin, cacheControl = requestOrigin(r), "private, max-age=3600"
+	}
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	set.URLs = append(set.URLs, sitemapURL{Loc: origin + URLFor("/")})
+	for rows.Next() {
+		var id string
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &updatedAt); err != nil {
+			log.Println("Erreur scan sitemap:", err)
+			continue
+		}
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     origin + URLFor("/tasting?id="+url.QueryEscape(id)),
+			LastMod: updatedAt.UTC().Format("2006-01-02"),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur rows sitemap:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl)
+	_, _ = w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(set); err != nil {
+		log.Println("Erreur encodage sitemap:", err)
+	}
+}
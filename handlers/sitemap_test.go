@@ -0,0 +1,36 @@
+package handlers
+
+import "testing"
+
+func TestSetAppURL(t *testing.T) {
+	saved := AppURL
+	t.Cleanup(func() { AppURL = saved })
+
+	tests := []struct {
+		name string
+		in   string
+		ok   bool
+		want string
+	}{
+		{"vide", "", true, ""},
+		{"https", "https://cacao.example.org", true, "https://cacao.example.org"},
+		{"barre finale", " https://cacao.example.org/ ", true, "https://cacao.example.org"},
+		{"port", "http://localhost:8080", true, "http://localhost:8080"},
+		{"chemin refusé", "https://example.org/cacao", false, "https://example.org"},
+		{"sans schéma", "cacao.example.org", false, "https://example.org"},
+		{"schéma inconnu", "ftp://cacao.example.org", false, "https://example.org"},
+		{"requête refusée", "https://cacao.example.org?x=1", false, "https://example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppURL = "https://example.org"
+			err := SetAppURL(tt.in)
+			if (err == nil) != tt.ok {
+				t.Fatalf("SetAppURL(%q) err = %v, ok attendu %v", tt.in, err, tt.ok)
+			}
+			if AppURL != tt.want {
+				t.Errorf("AppURL = %q, want %q", AppURL, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// ─── Journal des requêtes SQL lentes ───────────────────────────────────────
+//
+// queryContext / queryRowContext / execContext chronomètrent la requête et
+// journalisent (une ligne JSON) celles qui dépassent SlowQueryThreshold.
+// label est une étiquette fixe du point d'appel ("home.tastings"…) : ni le SQL
+// ni les paramètres ne sont journalisés.
+
+const DefaultSlowQueryThreshold = 500 * time.Millisecond
+
+// SlowQueryThreshold : seuil de journalisation (SLOW_QUERY_THRESHOLD)
+var SlowQueryThreshold = DefaultSlowQueryThreshold
+
+var slowQueryLog = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+func logSlowQuery(ctx context.Context, label string, start time.Time, err error) {
+	d := time.Since(start)
+	if d < SlowQueryThreshold {
+		return
+	}
+	attrs := []any{"label", label, "duration_ms", d.Milliseconds()}
+	if id := RequestIDFrom(ctx); id != "" {
+		attrs = append(attrs, "request_id", id)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+	slowQueryLog.WarnContext(ctx, "slow_query", attrs...)
+}
+
+func queryContext(ctx context.Context, label, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := DB.QueryContext(ctx, query, args...)
+	logSlowQuery(ctx, label, start, err)
+	return rows, err
+}
+
+// queryRowContext : la requête s'exécute dès l'appel, Scan ne fait que lire la ligne.
+func queryRowContext(ctx context.Context, label, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := DB.QueryRowContext(ctx, query, args...)
+	logSlowQuery(ctx, label, start, row.Err())
+	return row
+}
+
+func execContext(ctx context.Context, label, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := DB.ExecContext(ctx, query, args...)
+	logSlowQuery(ctx, label, start, err)
+	return res, err
+}
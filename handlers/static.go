@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ─── Fichiers statiques (cache navigateur) ─────────────────────────────────
+//
+// Assets "hashés" (app.3f9a1c2e.js, ou ?v=…) : le nom change à chaque version,
+// cache long et immutable. Autres fichiers : cache court. sw.js : no-cache,
+// sinon le navigateur garde l'ancien service worker et la PWA ne se met pas à jour.
+
+const (
+	DefaultStaticMaxAge       = time.Hour
+	DefaultStaticHashedMaxAge = 365 * 24 * time.Hour
+)
+
+// Durées de cache (STATIC_MAX_AGE, STATIC_HASHED_MAX_AGE)
+var (
+	StaticMaxAge       = DefaultStaticMaxAge
+	StaticHashedMaxAge = DefaultStaticHashedMaxAge
+)
+
+// Empreinte de contenu dans le nom : "name.<8+ hex>.ext"
+var hashedAssetRe = regexp.MustCompile(`\.[0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// Types explicites : la base mime du système (conteneur minimal) peut manquer
+var staticContentTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".json":        "application/json",
+	".webmanifest": "application/manifest+json",
+	".png":         "image/png",
+	".svg":         "image/svg+xml",
+	".ico":         "image/x-icon",
+	".webp":        "image/webp",
+	".woff2":       "font/woff2",
+}
+
+// staticCacheControl renvoie l'en-tête Cache-Control d'un fichier statique.
+func staticCacheControl(r *http.Request, name string) string {
+	switch {
+	case path.Base(name) == "sw.js":
+		return "no-cache"
+	case hashedAssetRe.MatchString(name) || r.URL.Query().Get("v") != "":
+		return fmt.Sprintf("public, max-age=%d, immutable", int(StaticHashedMaxAge.Seconds()))
+	default:
+		return fmt.Sprintf("public, max-age=%d", int(StaticMaxAge.Seconds()))
+	}
+}
+
+func setStaticHeaders(w http.ResponseWriter, r *http.Request, name string) {
+	ext := strings.ToLower(filepath.Ext(name))
+	ct, ok := staticContentTypes[ext]
+	if !ok {
+		ct = mime.TypeByExtension(ext)
+	}
+	if ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.Header().Set("Cache-Control", staticCacheControl(r, name))
+}
+
+// isRegularFile : seul un fichier existant reçoit les en-têtes de cache ; les 404
+// et listings de répertoire restent sans cache (une faute de frappe dans ?v= ne
+// doit pas être mémorisée comme immutable).
+func isRegularFile(fsys http.FileSystem, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// StaticHandler sert dir (à monter derrière http.StripPrefix) avec la politique de cache.
+func StaticHandler(dir string) http.Handler {
+	fsys := http.Dir(dir)
+	fs := http.FileServer(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isRegularFile(fsys, path.Clean("/"+r.URL.Path)) {
+			setStaticHeaders(w, r, r.URL.Path)
+		}
+		fs.ServeHTTP(w, r)
+	})
+}
+
+// ServeStaticFile sert un fichier isolé (sw.js, icônes à la racine) avec la même politique.
+func ServeStaticFile(w http.ResponseWriter, r *http.Request, file string) {
+	if fi, err := os.Stat(file); err == nil && fi.Mode().IsRegular() {
+		setStaticHeaders(w, r, file)
+	}
+	http.ServeFile(w, r, file)
+}
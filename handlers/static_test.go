@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticHandlerCacheOnlyOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("//"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "img"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h := StaticHandler(dir)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		cached bool
+	}{
+		{"fichier versionné", "/app.js?v=1", http.StatusOK, true},
+		{"faute de frappe", "/app.jss?v=1", http.StatusNotFound, false},
+		{"répertoire", "/img/?v=1", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Cache-Control") != ""; got != tt.cached {
+				t.Errorf("Cache-Control = %q, en cache attendu %v", w.Header().Get("Cache-Control"), tt.cached)
+			}
+		})
+	}
+}
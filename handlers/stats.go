@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ─── Profil de goût par famille d'arômes ───────────────────────────────────
+
+type familyStat struct {
+	Family   string  `json:"family"`
+	Count    int     `json:"count"`    // nombre de mentions d'arômes de la famille
+	Tastings int     `json:"tastings"` // dégustations contenant au moins un arôme de la famille
+	Share    float64 `json:"share"`    // part des mentions (0..1), pour un camembert
+}
+
+// FamilyStats agrège les arômes des dégustations par famille (archives incluses avec archived=1).
+// GET /api/v1/stats/families
+func FamilyStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(a.family,''), 'Autres'), COUNT(*), COUNT(DISTINCT t.id)
+		FROM tastings t
+		CROSS JOIN LATERAL unnest(t.aroma_ids) AS aid
+		JOIN aromas a ON a.id = aid
+		WHERE `+statsArchiveClause(r)+`
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+	`)
+	if err != nil {
+		log.Println("Erreur stats familles:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	defer rows.Close()
+
+	out := make([]familyStat, 0)
+	total := 0
+	for rows.Next() {
+		var s familyStat
+		if err := rows.Scan(&s.Family, &s.Count, &s.Tastings); err != nil {
+			log.Println("Erreur scan stats familles:", err)
+			continue
+		}
+		total += s.Count
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur rows stats familles:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	for i := range out {
+		out[i].Share = math.Round(float64(out[i].Count)/float64(total)*1000) / 1000
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":       true,
+		"total":    total,
+		"families": out,
+	})
+}
+
+// ─── Graphiques SVG (sans JS) ──────────────────────────────────────────────
+
+// Dimensions du graphique (viewBox, l'image reste responsive)
+const (
+	chartWidth   = 480
+	chartHeight  = 240
+	chartPadding = 28
+)
+
+// scoreDistribution compte les fiches notées vérifiant where par tranche de note (1..10).
+func scoreDistribution(ctx context.Context, where string) ([10]int, error) {
+	var buckets [10]int
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT LEAST(GREATEST(floor(score)::int, 1), 10), COUNT(*)
+		FROM tastings
+		WHERE score > 0 AND `+where+`
+		GROUP BY 1
+	`)
+	if err != nil {
+		return buckets, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return buckets, err
+		}
+		buckets[bucket-1] = count
+	}
+	return buckets, rows.Err()
+}
+
+// renderBarChartSVG dessine un histogramme simple (couleurs de l'app).
+func renderBarChartSVG(title string, labels []string, values []int) []byte {
+	maxVal := 1
+	for _, v := range values {
+		maxVal = max(maxVal, v)
+	}
+
+	plotW := float64(chartWidth - 2*chartPadding)
+	plotH := float64(chartHeight - 2*chartPadding)
+	slot := plotW / float64(len(values))
+	barW := slot * 0.7
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" role="img" aria-label="%s" font-family="sans-serif">`,
+		chartWidth, chartHeight, html.EscapeString(title))
+	fmt.Fprintf(&b, `<title>%s</title>`, html.EscapeString(title))
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#FBF6EF"/>`, chartWidth, chartHeight)
+
+	baseY := float64(chartPadding) + plotH
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#EDE4D7"/>`, chartPadding, baseY, chartWidth-chartPadding, baseY)
+
+	for i, v := range values {
+		h := plotH * float64(v) / float64(maxVal)
+		x := float64(chartPadding) + slot*float64(i) + (slot-barW)/2
+		cx := x + barW/2
+
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="3" fill="#C4843A"><title>%s : %d</title></rect>`,
+			x, baseY-h, barW, h, html.EscapeString(labels[i]), v)
+		if v > 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="middle" fill="#2C1810">%d</text>`, cx, baseY-h-4, v)
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" text-anchor="middle" fill="#7A6248">%s</text>`,
+			cx, baseY+16, html.EscapeString(labels[i]))
+	}
+
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
+
+// StatsChart rend un graphique SVG côté serveur (encart "Ma bibliothèque" de l'accueil,
+// affiché sans JS ; réutilisable tel quel à l'impression).
+// GET /stats/chart.svg?type=scores[&archived=1]
+func StatsChart(w http.ResponseWriter, r *http.Request) {
+	chartType := r.URL.Query().Get("type")
+	if chartType == "" {
+		chartType = "scores"
+	}
+	if chartType != "scores" {
+		renderError(w, r, http.StatusBadRequest, "Type de graphique inconnu (valeurs possibles : scores).")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	buckets, err := scoreDistribution(ctx, statsArchiveClause(r))
+	if err != nil {
+		log.Println("Erreur distribution notes:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
+	}
+
+	labels := make([]string, len(buckets))
+	for i := range buckets {
+		labels[i] = strconv.Itoa(i + 1)
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_, _ = w.Write(renderBarChartSVG("Répartition des notes", labels, buckets[:]))
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ─── Supabase Storage (helpers communs) ────────────────────────────────────
+
+// StorageBucket = bucket des photos (SUPABASE_STORAGE_BUCKET, défini au démarrage par main).
+var StorageBucket = defaultStorageBucket
+
+const defaultStorageBucket = "photos"
+
+// ParseStorageBucket valide le nom de bucket (vide = "photos").
+func ParseStorageBucket(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return defaultStorageBucket, nil
+	}
+	if strings.ContainsAny(s, "/?#% ") {
+		return "", fmt.Errorf("SUPABASE_STORAGE_BUCKET invalide: %q", s)
+	}
+	return s, nil
+}
+
+// storageConfig lit SUPABASE_URL + SUPABASE_SERVICE_ROLE_KEY (clé service-role).
+func storageConfig() (baseURL, key string, err error) {
+	baseURL = strings.TrimRight(os.Getenv("SUPABASE_URL"), "/")
+	key = strings.TrimSpace(os.Getenv("SUPABASE_SERVICE_ROLE_KEY"))
+	if baseURL == "" || key == "" {
+		return "", "", fmt.Errorf("SUPABASE_URL ou SUPABASE_SERVICE_ROLE_KEY manquant")
+	}
+	return baseURL, key, nil
+}
+
+func setStorageAuth(req *http.Request, key string) {
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("apikey", key)
+}
+
+// storageJSON envoie une requête JSON à l'API storage et décode la réponse dans out (si non nil).
+func storageJSON(ctx context.Context, method, url, key string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	setStorageAuth(req, key)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := uploadHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &httpError{Status: resp.Status, Body: string(raw)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// Upload : 3 tentatives max, backoff exponentiel (500ms, 1s) entre chaque
+const (
+	storageMaxAttempts = 3
+	storageBaseBackoff = 500 * time.Millisecond
+)
+
+// doStorageWithRetry exécute la requête construite par newReq (reconstruite à chaque
+// tentative, le body étant consommé). Retry sur erreur réseau et 5xx uniquement :
+// un 4xx (auth, validation) est renvoyé tout de suite. Respecte la deadline du contexte.
+func doStorageWithRetry(ctx context.Context, newReq func() (*http.Request, error)) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= storageMaxAttempts; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := uploadHTTPClient.Do(req)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return body, nil
+			}
+			lastErr = &httpError{Status: resp.Status, Body: string(body)}
+			if resp.StatusCode < 500 {
+				return nil, lastErr
+			}
+		} else {
+			lastErr = err
+		}
+
+		if attempt == storageMaxAttempts || ctx.Err() != nil {
+			break
+		}
+		wait := storageBaseBackoff << (attempt - 1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			break
+		}
+		log.Printf("Storage : tentative %d/%d échouée (%v), nouvel essai dans %s", attempt, storageMaxAttempts, lastErr, wait)
+
+		select {
+		case <-ctx.Done():
+			return nil, lastErr
+		case <-time.After(wait):
+		}
+	}
+	return nil, lastErr
+}
+
+// storageObject = entrée renvoyée par POST /storage/v1/object/list/{bucket}
+type storageObject struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Metadata  struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimetype"`
+	} `json:"metadata"`
+}
+
+const storageListPageSize = 1000
+
+// listStorageObjects liste tous les fichiers à la racine du bucket (pagination par offset).
+func listStorageObjects(ctx context.Context) ([]storageObject, error) {
+	baseURL, key, err := storageConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []storageObject
+	for offset := 0; ; offset += storageListPageSize {
+		var page []storageObject
+		err := storageJSON(ctx, http.MethodPost, baseURL+"/storage/v1/object/list/"+StorageBucket, key, map[string]any{
+			"prefix": "",
+			"limit":  storageListPageSize,
+			"offset": offset,
+			"sortBy": map[string]string{"column": "name", "order": "asc"},
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < storageListPageSize {
+			return all, nil
+		}
+	}
+}
+
+// deleteStorageObjects supprime une liste de fichiers du bucket (par lots).
+func deleteStorageObjects(ctx context.Context, names []string) error {
+	baseURL, key, err := storageConfig()
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(names); start += 100 {
+		end := min(start+100, len(names))
+		if err := storageJSON(ctx, http.MethodDelete, baseURL+"/storage/v1/object/"+StorageBucket, key,
+			map[string]any{"prefixes": names[start:end]}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storageObjectName extrait le nom de fichier d'une URL publique du bucket
+// (faux si l'URL pointe ailleurs : photo externe, autre bucket).
+func storageObjectName(photoURL string) (string, bool) {
+	marker := "/storage/v1/object/public/" + StorageBucket + "/"
+	i := strings.Index(photoURL, marker)
+	if i < 0 {
+		return "", false
+	}
+	name := photoURL[i+len(marker):]
+	if name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// uploadStorageObject envoie un fichier dans le bucket et renvoie son URL publique.
+func uploadStorageObject(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	baseURL, key, err := storageConfig()
+	if err != nil {
+		return "", err
+	}
+
+	uploadURL := baseURL + "/storage/v1/object/" + StorageBucket + "/" + name
+	_, err = doStorageWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		setStorageAuth(req, key)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("x-upsert", "true")
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return baseURL + "/storage/v1/object/public/" + StorageBucket + "/" + name, nil
+}
+
+// deletePhotoAsync supprime les fichiers d'une fiche supprimée (photo + variantes),
+// en best-effort et en arrière-plan (contexte détaché de ctx).
+func deletePhotoAsync(ctx context.Context, photoURLs ...string) {
+	var names []string
+	for _, u := range photoURLs {
+		if name, ok := storageObjectName(u); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	ctx, cancel := detach(ctx, 30*time.Second)
+	go func() {
+		defer cancel()
+		if err := deleteStorageObjects(ctx, names); err != nil {
+			log.Printf("Erreur suppression photo storage%s: %v", requestTag(ctx), err)
+		}
+	}()
+}
+
+// ─── Fichiers orphelins ────────────────────────────────────────────────────
+
+// Les fichiers trop récents sont ignorés : l'upload précède l'UPDATE de photo_url.
+const orphanMinAge = time.Hour
+
+type orphanFile struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// referencedPhotoNames renvoie les noms de fichiers encore utilisés par une dégustation.
+func referencedPhotoNames(ctx context.Context) (map[string]bool, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT photo_url, COALESCE(photo_variants::text,'{}')
+		FROM tastings WHERE COALESCE(photo_url,'') <> ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[string]bool{}
+	for rows.Next() {
+		var u, variantsRaw string
+		if err := rows.Scan(&u, &variantsRaw); err != nil {
+			return nil, err
+		}
+		names[path.Base(u)] = true
+		for _, v := range parsePhotoVariants(variantsRaw) {
+			names[path.Base(v)] = true
+		}
+	}
+	return names, rows.Err()
+}
+
+func findOrphans(ctx context.Context) ([]orphanFile, error) {
+	used, err := referencedPhotoNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := listStorageObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-orphanMinAge)
+	out := make([]orphanFile, 0)
+	for _, o := range objects {
+		// Les "dossiers" n'ont pas de métadonnées
+		if o.Name == "" || o.CreatedAt.IsZero() || used[o.Name] || o.CreatedAt.After(cutoff) {
+			continue
+		}
+		out = append(out, orphanFile{Name: o.Name, Size: o.Metadata.Size, CreatedAt: o.CreatedAt})
+	}
+	return out, nil
+}
+
+// StorageOrphans liste les photos du bucket qui ne sont plus référencées.
+// GET /admin/storage/orphans
+func StorageOrphans(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	orphans, err := findOrphans(ctx)
+	if err != nil {
+		log.Println("Erreur orphelins storage:", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "storage indisponible")
+		return
+	}
+
+	var total int64
+	for _, o := range orphans {
+		total += o.Size
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":          true,
+		"bucket":      StorageBucket,
+		"count":       len(orphans),
+		"total_bytes": total,
+		"orphans":     orphans,
+	})
+}
+
+// PurgeStorageOrphans supprime les orphelins (recalculés côté serveur, jamais la liste du client).
+// POST /admin/storage/orphans/purge
+func PurgeStorageOrphans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	orphans, err := findOrphans(ctx)
+	if err != nil {
+		log.Println("Erreur orphelins storage:", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "storage indisponible")
+		return
+	}
+
+	names := make([]string, 0, len(orphans))
+	var total int64
+	for _, o := range orphans {
+		names = append(names, o.Name)
+		total += o.Size
+	}
+
+	if err := deleteStorageObjects(ctx, names); err != nil {
+		log.Println("Erreur purge storage:", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "suppression storage échouée")
+		return
+	}
+
+	log.Printf("Purge storage : %d fichiers (%d octets)", len(names), total)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":          true,
+		"deleted":     len(names),
+		"freed_bytes": total,
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ─── Synchronisation incrémentale (clients mobiles) ────────────────────────
+//
+// Le client garde le "cursor" de la réponse et le renvoie en since au passage
+// suivant. updated_at est posé par trigger (migration 009) à l'heure de début
+// de la transaction : une écriture encore en cours au moment du curseur peut
+// porter une heure antérieure. since est donc reculé de syncOverlap ; une fiche
+// peut revenir deux fois, le client l'applique simplement à nouveau.
+
+const syncOverlap = 30 * time.Second
+
+// Tombstone = fiche supprimée depuis since (à retirer côté client).
+type Tombstone struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TastingChanges renvoie les fiches modifiées (archives comprises) et supprimées depuis since.
+// GET /api/v1/tastings/changes?since=2024-05-01T12:00:00Z
+func TastingChanges(w http.ResponseWriter, r *http.Request) {
+	raw := strings.TrimSpace(r.URL.Query().Get("since"))
+	if raw == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "since requis (RFC 3339) ; synchro complète : /api/v1/tastings")
+		return
+	}
+	since, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "since invalide (RFC 3339 attendu, ex: 2024-05-01T12:00:00Z)")
+		return
+	}
+	from := since.Add(-syncOverlap)
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	// Curseur pris avant les lectures, à l'heure de la base (pas celle du serveur)
+	var cursor time.Time
+	if err := queryRowContext(ctx, "changes.cursor", `SELECT now()`).Scan(&cursor); err != nil {
+		log.Println("Erreur curseur synchro:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	tastings, err := queryTastings(ctx, "changes.tastings",
+		`SELECT`+tastingSelectCols+`FROM tastings WHERE updated_at > $1 ORDER BY updated_at`, from)
+	if err != nil {
+		log.Println("Erreur fiches modifiées:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	// Une fiche restaurée (import de sauvegarde) après suppression n'est pas une pierre tombale
+	rows, err := queryContext(ctx, "changes.tombstones", `
+		SELECT tb.id, tb.deleted_at FROM tasting_tombstones tb
+		WHERE tb.deleted_at > $1
+		  AND NOT EXISTS (SELECT 1 FROM tastings t WHERE t.id::text = tb.id)
+		ORDER BY tb.deleted_at
+	`, from)
+	if err != nil {
+		log.Println("Erreur fiches supprimées:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+	defer rows.Close()
+
+	deleted := make([]Tombstone, 0)
+	for rows.Next() {
+		var tb Tombstone
+		if err := rows.Scan(&tb.ID, &tb.DeletedAt); err != nil {
+			log.Println("Erreur scan pierre tombale:", err)
+			continue
+		}
+		deleted = append(deleted, tb)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Erreur fiches supprimées:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":       true,
+		"since":    since.UTC().Format(time.RFC3339Nano),
+		"cursor":   cursor.UTC().Format(time.RFC3339Nano),
+		"tastings": tastings,
+		"deleted":  deleted,
+	})
+}
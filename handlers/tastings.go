@@ -3,60 +3,134 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"image"
-	"image/jpeg"
 	_ "image/png"
 	"io"
 	"log"
+	"maps"
 	"mime"
-	"mime/multipart"
 	"net/http"
-	"os"
+	"net/url"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/buckket/go-blurhash"
+	"github.com/lib/pq"
 	"github.com/nfnt/resize"
 )
 
 type Aroma struct {
-	ID       int
-	Name     string
-	Family   string
-	PhotoURL string
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Family   string `json:"family"`
+	PhotoURL string `json:"photo_url,omitempty"`
 }
 
 type Tasting struct {
-	ID          string
-	ProductName string
-	Maker       string
-	City        string
-	Score       float64
-	Mode        string
-	Notes       string
-	PhotoURL    string
-	CreatedAt   time.Time
-
-	AromaIDs   []int
-	AromaNames []string
-
-	Latitude  *float64
-	Longitude *float64
-
-	VueQuality   string
-	SnapQuality  string
-	MeltQuality  string
-	FinishLength string
+	ID          string    `json:"id"`
+	ProductName string    `json:"product_name"`
+	Maker       string    `json:"maker"`
+	City        string    `json:"city"`
+	Score       float64   `json:"score"`
+	Mode        string    `json:"mode"`
+	Notes       string    `json:"notes"`
+	PhotoURL    string    `json:"photo_url"`
+	BlurHash    string    `json:"blur_hash,omitempty"`
+	PhotoColor  string    `json:"photo_color"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Variantes redimensionnées : largeur (px) -> URL, pour srcset
+	PhotoVariants map[int]string `json:"photo_variants,omitempty"`
+
+	// Traitement de la photo en arrière-plan : pending | done | failed ("" : pas de photo)
+	PhotoStatus string `json:"photo_status,omitempty"`
+
+	// Hors bibliothèque et stats par défaut (voir archive.go)
+	Archived bool `json:"archived"`
+
+	// Incrémentée à chaque modification utilisateur (If-Match du PATCH, voir patch.go)
+	Version int `json:"version"`
+
+	AromaIDs   []int    `json:"aroma_ids"`
+	AromaNames []string `json:"aroma_names"`
+
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+
+	VueQuality   string `json:"vue_quality"`
+	SnapQuality  string `json:"snap_quality"`
+	MeltQuality  string `json:"melt_quality"`
+	FinishLength string `json:"finish_length"`
 }
 
 type HomeData struct {
 	Tastings    []Tasting
 	Aromas      []Aroma
 	Collections []Collection
+	Error       string
+	Stats       HomeStats
+
+	// Saisies d'un envoi refusé (ex: photo trop lourde), réinjectées dans le formulaire
+	Draft url.Values
+
+	// Filtres qualités actifs (mode approfondi)
+	QualityFilters []QualityFilter
+
+	// Mode présélectionné dans le formulaire d'ajout
+	DefaultMode string
+
+	// Lecture des arômes en échec (base injoignable, table absente) : picker vide
+	AromasUnavailable bool
+
+	// Page /incomplete (nil : bibliothèque complète)
+	Incomplete *IncompleteView
+}
+
+// QualityFilter = un filtre d'égalité actif sur une colonne qualité.
+type QualityFilter struct {
+	Param string
+	Label string
+	Value string
+}
+
+// Paramètres de filtre -> colonnes (liste blanche, jamais de colonne venant du client)
+var qualityFilterColumns = []struct {
+	Param  string
+	Column string
+	Label  string
+}{
+	{"vue", "vue_quality", "Vue"},
+	{"snap", "snap_quality", "Cassant"},
+	{"melt", "melt_quality", "Texture"},
+	{"finish", "finish_length", "Finale"},
+}
+
+// qualityFilterClauses construit les clauses WHERE (paramétrées) des filtres qualités.
+// argOffset = nombre d'arguments déjà utilisés dans la requête.
+func qualityFilterClauses(q url.Values, argOffset int) (clauses []string, args []any, active []QualityFilter) {
+	for _, f := range qualityFilterColumns {
+		v := strings.TrimSpace(q.Get(f.Param))
+		if v == "" {
+			continue
+		}
+		args = append(args, v)
+		clauses = append(clauses, fmt.Sprintf("lower(%s) = lower($%d)", f.Column, argOffset+len(args)))
+		active = append(active, QualityFilter{Param: f.Param, Label: f.Label, Value: v})
+	}
+	return clauses, args, active
 }
 
 var DB *sql.DB
@@ -70,29 +144,91 @@ const (
 	MaxUploadSize = 10 << 20 // 10MB
 	MaxImageWidth = 1200     // large max (mobile-friendly)
 	JpegQuality   = 80
+
+	// Plafond du corps de requête : au-delà de MaxUploadSize pour lire jusqu'au bout
+	// un formulaire dont la photo est trop lourde (et garder les saisies).
+	maxUploadBody = 3 * MaxUploadSize
+)
+
+// Message affiché quand la fiche est enregistrée mais pas la photo
+const photoUploadFailedMsg = "Dégustation enregistrée, mais l'envoi de la photo a échoué."
+
+// Timeouts par défaut des clients HTTP sortants (UPLOAD_TIMEOUT, GEO_TIMEOUT)
+const (
+	DefaultUploadTimeout = 20 * time.Second
+	DefaultGeoTimeout    = 6 * time.Second
 )
 
 // Client HTTP pour upload storage
 var uploadHTTPClient = &http.Client{
-	Timeout: 20 * time.Second,
+	Timeout: DefaultUploadTimeout,
+}
+
+// SetHTTPTimeouts règle les timeouts des clients storage et géocodage (à appeler au démarrage).
+func SetHTTPTimeouts(upload, geo time.Duration) {
+	uploadHTTPClient.Timeout = upload
+	geoHTTPClient.Timeout = geo
 }
 
 /* ─────────────────────────────────────────────
    Aromas helpers
 ───────────────────────────────────────────── */
 
-func GetAromas() []Aroma {
+// Cache mémoire des arômes (la table bouge rarement, elle est lue à chaque page)
+const aromaCacheTTL = 5 * time.Minute
+
+var aromaCache struct {
+	mu        sync.RWMutex
+	aromas    []Aroma
+	expiresAt time.Time
+}
+
+// InvalidateAromaCache force le rechargement des arômes à la prochaine lecture.
+func InvalidateAromaCache() {
+	aromaCache.mu.Lock()
+	aromaCache.aromas = nil
+	aromaCache.expiresAt = time.Time{}
+	aromaCache.mu.Unlock()
+}
+
+// GetAromas renvoie la liste des arômes (triée par famille puis nom), via le cache.
+// Une table vide donne une liste vide sans erreur ; err != nil signale une base
+// injoignable ou un schéma absent (déjà loggé : les appelants peuvent l'ignorer).
+func GetAromas() ([]Aroma, error) {
+	aromaCache.mu.RLock()
+	if aromaCache.aromas != nil && time.Now().Before(aromaCache.expiresAt) {
+		aromas := aromaCache.aromas
+		aromaCache.mu.RUnlock()
+		return aromas, nil
+	}
+	aromaCache.mu.RUnlock()
+
+	aromas, err := aromaLoader()
+	if err != nil {
+		log.Println("Erreur arômes:", err)
+		return nil, err
+	}
+	aromaCache.mu.Lock()
+	aromaCache.aromas = aromas
+	aromaCache.expiresAt = time.Now().Add(aromaCacheTTL)
+	aromaCache.mu.Unlock()
+	return aromas, nil
+}
+
+// Lecture de la table des arômes par GetAromas (remplaçable pour tester le cache)
+var aromaLoader = loadAromas
+
+func loadAromas() ([]Aroma, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	rows, err := DB.QueryContext(ctx, `SELECT id, name, family FROM aromas ORDER BY family, name`)
+	rows, err := queryContext(ctx, "aromas.list", `SELECT id, name, family FROM aromas ORDER BY family, name`)
 	if err != nil {
-		log.Println("Erreur arômes:", err)
-		return nil
+		return nil, err
 	}
 	defer rows.Close()
 
-	var aromas []Aroma
+	aromas := make([]Aroma, 0)
 	for rows.Next() {
 		var a Aroma
 		if err := rows.Scan(&a.ID, &a.Name, &a.Family); err != nil {
@@ -101,10 +237,13 @@ func GetAromas() []Aroma {
 		}
 		aromas = append(aromas, a)
 	}
-	if err := rows.Err(); err != nil {
-		log.Println("Erreur rows arômes:", err)
-	}
-	return aromas
+	return aromas, rows.Err()
+}
+
+// aromaNameMap : id -> nom des arômes connus (vide si la base est injoignable).
+func aromaNameMap() map[int]string {
+	aromas, _ := GetAromas()
+	return aromaMapFromSlice(aromas)
 }
 
 func aromaMapFromSlice(aromas []Aroma) map[int]string {
@@ -153,7 +292,13 @@ const tastingSelectCols = `
 	COALESCE(vue_quality,''),
 	COALESCE(snap_quality,''),
 	COALESCE(melt_quality,''),
-	COALESCE(finish_length,'')
+	COALESCE(finish_length,''),
+	COALESCE(blur_hash,''),
+	COALESCE(photo_color,''),
+	COALESCE(photo_variants::text,'{}'),
+	photo_status,
+	archived,
+	version
 `
 
 // scanTasting scanne une ligne DB en Tasting.
@@ -162,7 +307,7 @@ func scanTasting(row interface {
 	Scan(...any) error
 }, aromaMap map[int]string) (Tasting, error) {
 	var t Tasting
-	var aromaIDsRaw string
+	var aromaIDsRaw, variantsRaw string
 	var lat, lng sql.NullFloat64
 
 	err := row.Scan(
@@ -170,10 +315,17 @@ func scanTasting(row interface {
 		&t.Score, &t.Mode, &t.Notes, &t.PhotoURL,
 		&lat, &lng, &t.CreatedAt, &aromaIDsRaw,
 		&t.VueQuality, &t.SnapQuality, &t.MeltQuality, &t.FinishLength,
+		&t.BlurHash, &t.PhotoColor, &variantsRaw, &t.PhotoStatus,
+		&t.Archived, &t.Version,
 	)
 	if err != nil {
 		return t, err
 	}
+	t.PhotoVariants = parsePhotoVariants(variantsRaw)
+
+	if t.PhotoColor == "" {
+		t.PhotoColor = neutralPhotoColor
+	}
 
 	if lat.Valid {
 		v := lat.Float64
@@ -193,23 +345,96 @@ func scanTasting(row interface {
 	return t, nil
 }
 
+// queryTastings exécute une requête renvoyant des colonnes tastingSelectCols
+// et scanne toutes les lignes (les lignes illisibles sont loggées et ignorées).
+func queryTastings(ctx context.Context, label, query string, args ...any) ([]Tasting, error) {
+	rows, err := queryContext(ctx, label, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aMap := aromaNameMap()
+
+	tastings := make([]Tasting, 0)
+	for rows.Next() {
+		t, err := scanTasting(rows, aMap)
+		if err != nil {
+			log.Println("Erreur scan:", err)
+			continue
+		}
+		tastings = append(tastings, t)
+	}
+	return tastings, rows.Err()
+}
+
+// HomeStats = chiffres clés de l'en-tête (indépendants des filtres / du chargement des fiches).
+type HomeStats struct {
+	TotalTastings   int // hors archives
+	ArchivedCount   int
+	IncompleteCount int // hors archives : sans photo, notes ou note
+	CollectionCount int
+	AvgScore        string // "" si aucune fiche notée
+	WeightedAvg     string // pondérée par mode (ScoreWeightQuick / ScoreWeightDeep)
+}
+
+// GetHomeStats calcule les stats via des agrégats (pas de chargement des lignes).
+func GetHomeStats(ctx context.Context) (HomeStats, error) {
+	var st HomeStats
+
+	if err := queryRowContext(ctx, "home.counts", `
+		SELECT COUNT(*) FILTER (WHERE NOT archived), COUNT(*) FILTER (WHERE archived),
+			COUNT(*) FILTER (WHERE NOT archived AND `+incompleteAny+`)
+		FROM tastings
+	`).Scan(&st.TotalTastings, &st.ArchivedCount, &st.IncompleteCount); err != nil {
+		return st, err
+	}
+	if err := queryRowContext(ctx, "home.collection_count", `SELECT COUNT(*) FROM collections`).Scan(&st.CollectionCount); err != nil {
+		return st, err
+	}
+
+	// moyenne uniquement sur les fiches notées (comme les collections)
+	avgs, err := scoreAverages(ctx, notArchived)
+	if err != nil {
+		return st, err
+	}
+	st.AvgScore, st.WeightedAvg = avgs.Simple, avgs.Weighted
+	return st, nil
+}
+
 /* ─────────────────────────────────────────────
    Pages
 ───────────────────────────────────────────── */
 
 func Home(w http.ResponseWriter, r *http.Request) {
+	renderHome(w, r, http.StatusOK, strings.TrimSpace(r.URL.Query().Get("error")), nil)
+}
+
+// renderHome affiche la bibliothèque ; errMsg et draft servent à réafficher un ajout refusé.
+func renderHome(w http.ResponseWriter, r *http.Request, status int, errMsg string, draft url.Values) {
+	renderLibrary(w, r, status, errMsg, draft, nil)
+}
+
+// renderLibrary affiche index.html, restreint aux fiches à compléter si incomplete != nil.
+func renderLibrary(w http.ResponseWriter, r *http.Request, status int, errMsg string, draft url.Values, incomplete *IncompleteView) {
 	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
 	defer cancel()
 
-	rows, err := DB.QueryContext(ctx, `SELECT`+tastingSelectCols+`FROM tastings ORDER BY created_at DESC`)
+	clauses, args, activeFilters := qualityFilterClauses(r.URL.Query(), 0)
+	if incomplete != nil {
+		clauses = append(clauses, incomplete.clause)
+	}
+	where := " WHERE " + strings.Join(append([]string{notArchived}, clauses...), " AND ")
+
+	rows, err := queryContext(ctx, "home.tastings", `SELECT`+tastingSelectCols+`FROM tastings`+where+` ORDER BY created_at DESC`, args...)
 	if err != nil {
 		log.Println("Erreur requête:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
 	defer rows.Close()
 
-	allAromas := GetAromas()
+	allAromas, aromaErr := GetAromas()
 	aMap := aromaMapFromSlice(allAromas)
 
 	var tastings []Tasting
@@ -223,58 +448,209 @@ func Home(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := rows.Err(); err != nil {
 		log.Println("Erreur rows tastings:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
 
+	stats, err := GetHomeStats(ctx)
+	if err != nil {
+		log.Println("Erreur stats home:", err)
+	}
+
 	data := HomeData{
+		Stats:       stats,
 		Tastings:    tastings,
 		Aromas:      allAromas,
 		Collections: GetCollections(),
+		Error:       errMsg,
+		Draft:       draft,
+
+		QualityFilters: activeFilters,
+		DefaultMode:    DefaultMode,
+
+		AromasUnavailable: aromaErr != nil,
+
+		Incomplete: incomplete,
 	}
 
-	if err := Tmpl.ExecuteTemplate(w, "index.html", data); err != nil {
+	// Rendu en mémoire : une erreur de template peut encore produire une page 500
+	var buf bytes.Buffer
+	if err := Tmpl.ExecuteTemplate(&buf, "index.html", data); err != nil {
 		log.Println("Erreur template:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+		return
 	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
 }
 
 /* ─────────────────────────────────────────────
    Add / Update helpers
 ───────────────────────────────────────────── */
 
-// buildNotes assemble les champs du formulaire (rapide ou approfondi) en une note complète.
+// Longueur max d'une note (en caractères, pas en octets)
+const MaxNotesLength = 5000
+
+const truncatedSuffix = "… [tronqué]"
+
+// sanitizeText retire les caractères de contrôle (sauf retours à la ligne / tabulations)
+// et tronque au-delà de max caractères, avec un indicateur.
+func sanitizeText(s string, max int) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r == '\n' || r == '\t' {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.IsControl(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	s = strings.TrimSpace(b.String())
+
+	runes := []rune(s)
+	if max > 0 && len(runes) > max {
+		keep := max - len([]rune(truncatedSuffix))
+		if keep < 0 {
+			keep = 0
+		}
+		s = strings.TrimSpace(string(runes[:keep])) + truncatedSuffix
+	}
+	return s
+}
+
+// buildNotes assemble les notes libres du formulaire.
+// Les qualités du mode approfondi (vue/cassant/texture/finale) ne sont plus
+// recopiées ici : elles vivent uniquement dans leurs colonnes dédiées.
 func buildNotes(r *http.Request) string {
-	mode := strings.TrimSpace(r.FormValue("mode"))
-	if mode != "deep" {
+	return sanitizeText(buildRawNotes(r), MaxNotesLength)
+}
+
+func buildRawNotes(r *http.Request) string {
+	if validateMode(r.FormValue("mode")) != ModeDeep {
 		return strings.TrimSpace(r.FormValue("notes"))
 	}
 
 	var parts []string
+	for _, field := range []string{"notes_cassant", "notes_finale", "notes"} {
+		if v := strings.TrimSpace(r.FormValue(field)); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
 
-	if v := strings.TrimSpace(r.FormValue("vue_quality")); v != "" {
-		parts = append(parts, "Vue : "+v)
+// Modes de dégustation
+const (
+	ModeQuick = "quick"
+	ModeDeep  = "deep"
+)
+
+// allowedModes : modes acceptés. Nouveau mode = une ligne ici.
+var allowedModes = []string{ModeQuick, ModeDeep}
+
+// DefaultMode : mode retenu quand le formulaire n'en précise pas, et présélectionné
+// dans le formulaire d'ajout (DEFAULT_TASTING_MODE, quick par défaut).
+var DefaultMode = ModeQuick
+
+// SetDefaultMode configure le mode par défaut (vide : quick ; inconnu : erreur).
+func SetDefaultMode(s string) error {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		s = ModeQuick
 	}
-	if v := strings.TrimSpace(r.FormValue("snap_quality")); v != "" {
-		parts = append(parts, "Cassant : "+v)
+	if !slices.Contains(allowedModes, s) {
+		return fmt.Errorf("mode inconnu %q (valeurs possibles : %s)", s, strings.Join(allowedModes, ", "))
 	}
-	if v := strings.TrimSpace(r.FormValue("notes_cassant")); v != "" {
-		parts = append(parts, v)
+	DefaultMode = s
+	return nil
+}
+
+// validateMode normalise le mode du formulaire ; vide ou inconnu => DefaultMode.
+func validateMode(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if slices.Contains(allowedModes, s) {
+		return s
+	}
+	return DefaultMode
+}
+
+// Champs obligatoires à l'ajout (REQUIRE_PHOTO, REQUIRE_LOCATION ; optionnels par défaut).
+// Appliqués au formulaire et à l'API ; l'ajout rapide renvoie alors vers le formulaire
+// complet. L'import CSV en est exempté : il reprend un historique, sans photos.
+var (
+	RequirePhoto    bool
+	RequireLocation bool
+)
+
+// missingRequired renvoie le message d'erreur du premier champ obligatoire manquant ("" si rien).
+func missingRequired(hasPhoto, hasLocation bool) string {
+	switch {
+	case RequirePhoto && !hasPhoto:
+		return "Photo obligatoire : ajoute une photo de la dégustation."
+	case RequireLocation && !hasLocation:
+		return "Lieu obligatoire : choisis un lieu ou utilise ta position."
+	}
+	return ""
+}
+
+// Longueurs max des champs texte (en caractères)
+const (
+	MaxProductNameLength = 200
+	MaxMakerLength       = 200
+	MaxCityLength        = 120
+)
+
+// validateField nettoie (normalizeText) une valeur et vérifie sa longueur max.
+func validateField(name, value string, max int) (string, error) {
+	value = normalizeText(value)
+	if n := utf8.RuneCountInString(value); n > max {
+		return value, fmt.Errorf("%s trop long (%d caractères, max %d)", name, n, max)
 	}
-	if v := strings.TrimSpace(r.FormValue("melt_quality")); v != "" {
-		parts = append(parts, "Texture : "+v)
+	return value, nil
+}
+
+// validateTastingText applique validateField aux champs texte principaux d'une fiche.
+func validateTastingText(r *http.Request) (productName, maker, city string, err error) {
+	if productName, err = validateField("Nom du produit", r.FormValue("product_name"), MaxProductNameLength); err != nil {
+		return
 	}
-	if v := strings.TrimSpace(r.FormValue("finish_length")); v != "" {
-		parts = append(parts, "Finale : "+v)
+	if maker, err = validateField("Chocolatier", r.FormValue("maker"), MaxMakerLength); err != nil {
+		return
 	}
-	if v := strings.TrimSpace(r.FormValue("notes_finale")); v != "" {
-		parts = append(parts, v)
+	city, err = validateField("Ville", r.FormValue("city"), MaxCityLength)
+	return
+}
+
+// softWarnings relève les saisies suspectes mais valides : la fiche est
+// enregistrée quand même, l'utilisateur est seulement prévenu.
+func softWarnings(score float64, notes, mode string, qualities ...string) []string {
+	var warnings []string
+	if score >= 10 && notes == "" {
+		warnings = append(warnings, "aucune note ajoutée pour un 10/10")
 	}
-	if v := strings.TrimSpace(r.FormValue("notes")); v != "" {
-		parts = append(parts, v)
+	if mode == ModeDeep && !slices.ContainsFunc(qualities, func(q string) bool { return q != "" }) {
+		warnings = append(warnings, "aucune qualité renseignée en mode approfondi")
 	}
+	return warnings
+}
 
-	return strings.Join(parts, "\n")
+// savedFlash : message affiché (?error=) après un enregistrement réussi,
+// "" si rien à signaler.
+func savedFlash(photoFailed bool, warnings []string) string {
+	if photoFailed {
+		warnings = append([]string{"l'envoi de la photo a échoué"}, warnings...)
+	}
+	if len(warnings) == 0 {
+		return ""
+	}
+	return "Dégustation enregistrée, mais " + strings.Join(warnings, ", ") + "."
 }
 
 // parse float safe
@@ -290,6 +666,36 @@ func parseFloatOrNull(s string) sql.NullFloat64 {
 	return sql.NullFloat64{Float64: f, Valid: true}
 }
 
+// errCoordsOutOfRange : latitude hors [-90,90] ou longitude hors [-180,180] (NaN/Inf compris).
+var errCoordsOutOfRange = errors.New("Coordonnées GPS invalides (latitude entre -90 et 90, longitude entre -180 et 180)")
+
+// parseCoordinates lit latitude/longitude du formulaire ; une valeur hors limites
+// donne NULL pour les deux (pas de point aberrant sur la carte) et errCoordsOutOfRange.
+func parseCoordinates(r *http.Request) (lat, lng sql.NullFloat64, err error) {
+	lat = parseFloatOrNull(r.FormValue("latitude"))
+	lng = parseFloatOrNull(r.FormValue("longitude"))
+	if !coordinateInRange(lat, 90) || !coordinateInRange(lng, 180) {
+		return sql.NullFloat64{}, sql.NullFloat64{}, errCoordsOutOfRange
+	}
+	return lat, lng, nil
+}
+
+func coordinateInRange(v sql.NullFloat64, limit float64) bool {
+	return !v.Valid || (v.Float64 >= -limit && v.Float64 <= limit)
+}
+
+// formCoordinates : parseCoordinates pour l'ajout / l'édition. Hors limites, le
+// formulaire classique reçoit l'erreur (à réafficher) ; en AJAX la fiche est
+// enregistrée sans position (err nil, lat/lng NULL).
+func formCoordinates(r *http.Request) (lat, lng sql.NullFloat64, err error) {
+	lat, lng, err = parseCoordinates(r)
+	if err != nil && wantsJSON(r) {
+		log.Println("Coordonnées ignorées:", r.FormValue("latitude"), r.FormValue("longitude"))
+		return lat, lng, nil
+	}
+	return lat, lng, err
+}
+
 func buildPgIntArray(ids []string) string {
 	if len(ids) == 0 {
 		return "{}"
@@ -317,33 +723,37 @@ func buildPgIntArray(ids []string) string {
 
 func AddTasting(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
 	// Limite dure : si quelqu’un tente 200MB, on coupe net.
-	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBody)
 
-	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
-		log.Println("Erreur ParseMultipartForm:", err)
-		http.Error(w, "Fichier trop lourd (max 10MB)", http.StatusBadRequest)
+	if !parseUploadForm(w, r) {
 		return
 	}
-
-	productName := strings.TrimSpace(r.FormValue("product_name"))
-	if productName == "" {
-		http.Redirect(w, r, "/", http.StatusFound)
+	// Photo lisible mais trop lourde : on réaffiche le formulaire avec les saisies
+	if photoTooLarge(r) {
+		uploadTooLarge(w, r, r.PostForm)
 		return
 	}
 
-	maker := strings.TrimSpace(r.FormValue("maker"))
-	city := strings.TrimSpace(r.FormValue("city"))
-
-	mode := strings.TrimSpace(r.FormValue("mode"))
-	if mode == "" {
-		mode = "quick"
+	productName, maker, city, err := validateTastingText(r)
+	if err == nil && productName == "" {
+		err = errors.New("Nom du produit obligatoire")
+	}
+	if err != nil {
+		if wantsJSON(r) {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		http.Redirect(w, r, URLFor("/?error="+url.QueryEscape(err.Error())), http.StatusFound)
+		return
 	}
 
+	mode := validateMode(r.FormValue("mode"))
+
 	notes := buildNotes(r)
 
 	vueQ := strings.TrimSpace(r.FormValue("vue_quality"))
@@ -352,7 +762,7 @@ func AddTasting(w http.ResponseWriter, r *http.Request) {
 	finishL := strings.TrimSpace(r.FormValue("finish_length"))
 
 	// En mode quick, on vide pour ne pas polluer
-	if mode != "deep" {
+	if mode != ModeDeep {
 		vueQ, snapQ, meltQ, finishL = "", "", "", ""
 	}
 
@@ -363,73 +773,268 @@ func AddTasting(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	lat := parseFloatOrNull(r.FormValue("latitude"))
-	lng := parseFloatOrNull(r.FormValue("longitude"))
+	warnings := softWarnings(scoreVal, notes, mode, vueQ, snapQ, meltQ, finishL)
+
+	// Hors limites : formulaire réaffiché ; en AJAX la fiche est enregistrée sans position
+	lat, lng, err := formCoordinates(r)
+	if err != nil {
+		renderHome(w, r, http.StatusBadRequest, err.Error(), r.PostForm)
+		return
+	}
+
+	// Photo lue tout de suite, traitée en arrière-plan après l'insertion
+	var warning, photoStatus string
+	photoData, err := readPhotoUpload(r)
+	if err != nil {
+		log.Println("Erreur lecture photo:", err)
+		warning = photoUploadFailedMsg
+	}
+	if photoData != nil {
+		photoStatus = PhotoPending
+	}
 
-	aromaArray := buildPgIntArray(r.Form["aroma_ids"])
+	if msg := missingRequired(photoData != nil, lat.Valid && lng.Valid); msg != "" {
+		if wantsJSON(r) {
+			writeError(w, http.StatusBadRequest, "bad_request", msg)
+			return
+		}
+		renderHome(w, r, http.StatusBadRequest, msg, r.PostForm)
+		return
+	}
 
-	// 1) Transaction DB : on crée la dégustation, on récupère l’ID
+	// 1) Transaction DB : on crée les arômes saisis librement + la dégustation, on récupère l’ID
 	var tastingID string
 	{
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
-		tx, err := DB.BeginTx(ctx, nil)
+		var aromasCreated bool
+		err := withTx(ctx, func(tx *sql.Tx) error {
+			aromaArray, created, err := formAromaArray(ctx, tx, r)
+			if err != nil {
+				return fmt.Errorf("arômes libres: %w", err)
+			}
+			aromasCreated = created
+
+			tastingID, err = insertTasting(ctx, tx, newTasting{
+				ProductName: productName, Maker: maker, City: city,
+				Score: scoreVal, Notes: notes, Mode: mode,
+				AromaArray: aromaArray, Lat: lat, Lng: lng,
+				VueQuality: vueQ, SnapQuality: snapQ, MeltQuality: meltQ, FinishLength: finishL,
+				PhotoStatus: photoStatus,
+			})
+			return err
+		})
 		if err != nil {
-			log.Println("Erreur BeginTx:", err)
-			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			log.Println("Erreur insertion:", err)
+			renderError(w, r, http.StatusInternalServerError, "La fiche n'a pas pu être enregistrée, réessaie dans un instant.")
 			return
 		}
-		defer tx.Rollback()
+		if aromasCreated {
+			InvalidateAromaCache()
+		}
+	}
 
-		err = tx.QueryRowContext(ctx, `
-			INSERT INTO tastings (
-				product_name, maker, city, score, notes, mode,
-				aroma_ids, latitude, longitude,
-				vue_quality, snap_quality, melt_quality, finish_length,
-				photo_url
-			)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
-			RETURNING id
-		`,
-			productName, maker, city, scoreVal, notes, mode,
-			aromaArray, lat, lng,
-			vueQ, snapQ, meltQ, finishL,
-			"", // photo_url sera mis à jour après upload si dispo
-		).Scan(&tastingID)
+	publishTastingEvent("tasting.added", tastingID)
 
-		if err != nil {
-			log.Println("Erreur insertion:", err)
-			http.Error(w, "Erreur sauvegarde", http.StatusInternalServerError)
-			return
-		}
+	// 2) Photo confiée aux workers : la réponse n'attend pas l'upload (photo_status à suivre)
+	if photoData != nil {
+		queuePhoto(r, photoJob{TastingID: tastingID, Data: photoData})
+	}
 
-		if err := tx.Commit(); err != nil {
-			log.Println("Erreur commit:", err)
-			http.Error(w, "Erreur sauvegarde", http.StatusInternalServerError)
-			return
-		}
+	// PWA : la fiche créée (arômes résolus) pour l'ajouter à la liste sans recharger
+	if wantsJSON(r) {
+		writeCreatedTasting(w, r, tastingID, warning, warnings)
+		return
+	}
+
+	redirectTo := URLFor("/")
+	if msg := savedFlash(warning != "", warnings); msg != "" {
+		redirectTo += "?error=" + url.QueryEscape(msg)
+	}
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+// writeCreatedTasting répond 201 avec la fiche relue en base.
+// photo_url peut être vide : pas de photo, ou photo encore en traitement (photo_status).
+// warnings : avertissements de saisie (softWarnings), toujours présent ([] si aucun).
+func writeCreatedTasting(w http.ResponseWriter, r *http.Request, id, warning string, warnings []string) {
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	w.Header().Set("Location", URLFor("/tasting?id="+url.QueryEscape(id)))
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+	resp := map[string]any{"ok": true, "id": id, "warnings": warnings}
+
+	row := DB.QueryRowContext(ctx, `SELECT`+tastingSelectCols+`FROM tastings WHERE id = $1`, id)
+	if t, err := scanTasting(row, aromaNameMap()); err == nil {
+		resp["tasting"] = t
+	} else {
+		// La fiche est bien créée : le client se contentera de l'id
+		log.Println("Erreur relecture fiche créée:", err)
+	}
+	if warning != "" {
+		resp["warning"] = warning
 	}
+	writeJSON(w, http.StatusCreated, resp)
+}
 
-	// 2) Upload photo (hors transaction DB)
-	file, header, err := r.FormFile("photo")
+// parseUploadForm lit le formulaire multipart ; en cas d'échec la réponse est déjà écrite.
+// Corps au-delà de maxUploadBody : saisies perdues (lecture interrompue), sinon formulaire malformé.
+func parseUploadForm(w http.ResponseWriter, r *http.Request) bool {
+	err := r.ParseMultipartForm(MaxUploadSize)
 	if err == nil {
-		defer file.Close()
+		return true
+	}
 
-		photoURL, upErr := processAndUploadImage(r.Context(), file, header, tastingID)
-		if upErr != nil {
-			log.Println("Erreur upload photo:", upErr)
-		} else {
-			ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
-			defer cancel()
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		uploadTooLarge(w, r, nil)
+		return false
+	}
+	log.Println("Erreur ParseMultipartForm:", err)
+	renderError(w, r, http.StatusBadRequest, "Formulaire invalide ou incomplet, réessaie.")
+	return false
+}
+
+// photoTooLarge : taille de la photo jointe lue dans l'en-tête multipart,
+// sans ouvrir le fichier (formulaire déjà analysé par parseUploadForm).
+func photoTooLarge(r *http.Request) bool {
+	if r.MultipartForm == nil {
+		return false
+	}
+	files := r.MultipartForm.File["photo"]
+	return len(files) > 0 && files[0].Size > MaxUploadSize
+}
 
-			if _, upDBErr := DB.ExecContext(ctx, `UPDATE tastings SET photo_url=$1 WHERE id=$2`, photoURL, tastingID); upDBErr != nil {
-				log.Println("Erreur update photo_url:", upDBErr)
+// uploadTooLarge répond 413 : JSON avec la limite pour l'AJAX, sinon le formulaire
+// d'ajout réaffiché avec draft (page d'erreur si les saisies n'ont pas pu être lues).
+func uploadTooLarge(w http.ResponseWriter, r *http.Request, draft url.Values) {
+	limitMB := MaxUploadSize >> 20
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+			"ok": false,
+			"error": map[string]string{
+				"code":    errorCode(http.StatusRequestEntityTooLarge),
+				"message": fmt.Sprintf("Photo trop lourde (max %d Mo).", limitMB),
+			},
+			"max_bytes": MaxUploadSize,
+		})
+		return
+	}
+	if draft == nil {
+		renderError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Envoi trop volumineux : la photo ne doit pas dépasser %d Mo.", limitMB))
+		return
+	}
+	renderHome(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Photo trop lourde (max %d Mo) : tes saisies sont conservées, choisis une image plus légère.", limitMB), draft)
+}
+
+// newTasting = champs d'une fiche à créer (la photo est ajoutée après, voir savePhoto).
+type newTasting struct {
+	ProductName, Maker, City string
+	Score                    float64
+	Notes                    string
+	Mode                     string
+	AromaArray               string // tableau Postgres "{1,2}" (buildPgIntArray)
+	Lat, Lng                 sql.NullFloat64
+
+	VueQuality, SnapQuality, MeltQuality, FinishLength string
+
+	PhotoStatus string // PhotoPending si une photo suit
+}
+
+// insertTasting insère la fiche dans la transaction et renvoie son id.
+func insertTasting(ctx context.Context, tx *sql.Tx, t newTasting) (string, error) {
+	if t.AromaArray == "" {
+		t.AromaArray = "{}"
+	}
+
+	var id string
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO tastings (
+			product_name, maker, city, score, notes, mode,
+			aroma_ids, latitude, longitude,
+			vue_quality, snap_quality, melt_quality, finish_length,
+			photo_url, photo_status
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
+		RETURNING id
+	`,
+		t.ProductName, t.Maker, t.City, t.Score, t.Notes, validateMode(t.Mode),
+		t.AromaArray, t.Lat, t.Lng,
+		t.VueQuality, t.SnapQuality, t.MeltQuality, t.FinishLength,
+		"", // photo_url sera mis à jour après upload si dispo
+		t.PhotoStatus,
+	).Scan(&id)
+	return id, err
+}
+
+// QuickAdd crée une fiche minimale (nom seul) puis ouvre son édition pour la compléter.
+// Pensé pour un raccourci d'écran d'accueil : sans nom, affiche un formulaire d'un champ.
+// GET|POST /quickadd?product_name=...
+func QuickAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		renderError(w, r, http.StatusMethodNotAllowed, "Méthode non autorisée.")
+		return
+	}
+	// Photo ou lieu obligatoires : le nom seul ne suffit pas, direction le formulaire
+	// complet (le raccourci PWA du manifest statique y mène aussi)
+	if msg := missingRequired(false, false); msg != "" {
+		http.Redirect(w, r, URLFor("/?error="+url.QueryEscape("Ajout rapide indisponible. "+msg)), http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		renderError(w, r, http.StatusBadRequest, "Formulaire invalide.")
+		return
+	}
+
+	name, err := validateField("Nom du produit", r.FormValue("product_name"), MaxProductNameLength)
+	if err == nil && name == "" {
+		if r.Method == http.MethodGet {
+			if err := Tmpl.ExecuteTemplate(w, "quickadd.html", nil); err != nil {
+				log.Println("Erreur template quickadd:", err)
 			}
+			return
+		}
+		err = errors.New("Nom du produit obligatoire")
+	}
+	if err != nil {
+		renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// GET avec nom = écriture : mêmes garde-fous que les POST (démo, rate limit)
+	if r.Method == http.MethodGet {
+		if ReadOnly {
+			renderError(w, r, http.StatusForbidden, "Mode démo : l'application est en lecture seule.")
+			return
+		}
+		if !allowWrite(w, r) {
+			return
 		}
 	}
 
-	http.Redirect(w, r, "/", http.StatusFound)
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var id string
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = insertTasting(ctx, tx, newTasting{ProductName: name, Mode: ModeQuick})
+		return err
+	})
+	if err != nil {
+		log.Println("Erreur ajout rapide:", err)
+		renderError(w, r, http.StatusInternalServerError, "La fiche n'a pas pu être enregistrée, réessaie dans un instant.")
+		return
+	}
+
+	publishTastingEvent("tasting.added", id)
+	http.Redirect(w, r, URLFor("/edit?id="+url.QueryEscape(id)), http.StatusSeeOther)
 }
 
 /* ─────────────────────────────────────────────
@@ -438,47 +1043,67 @@ func AddTasting(w http.ResponseWriter, r *http.Request) {
 
 func DeleteTasting(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		renderError(w, r, http.StatusMethodNotAllowed, "Méthode non autorisée.")
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		renderError(w, r, http.StatusBadRequest, "Formulaire invalide.")
 		return
 	}
 	id := strings.TrimSpace(r.FormValue("id"))
 	if id == "" {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		renderError(w, r, http.StatusBadRequest, "Identifiant de dégustation manquant.")
 		return
 	}
 
-	// Supprimer d'abord les liaisons collections (si pas de CASCADE)
-	{
-		ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
-		defer cancel()
-		if _, err := DB.ExecContext(ctx, `DELETE FROM collection_tastings WHERE tasting_id = $1`, id); err != nil {
-			log.Println("Erreur suppression collection_tastings:", err)
-		}
-	}
+	// Liaisons collections (si pas de CASCADE) puis la fiche, en une seule transaction.
+	// La photo n'est supprimée du storage qu'une fois le commit réussi.
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
 
-	{
-		ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
-		defer cancel()
-		if _, err := DB.ExecContext(ctx, `DELETE FROM tastings WHERE id = $1`, id); err != nil {
-			log.Println("Erreur suppression:", err)
+	var photoURL, variantsRaw string
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT COALESCE(photo_url,''), COALESCE(photo_variants::text,'{}')
+			FROM tastings WHERE id = $1 FOR UPDATE`, id).Scan(&photoURL, &variantsRaw)
+		if err != nil {
+			return err
 		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM collection_tastings WHERE tasting_id = $1`, id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM tastings WHERE id = $1`, id)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		renderError(w, r, http.StatusNotFound, "Cette dégustation n'existe pas (ou plus).")
+		return
+	}
+	if err != nil {
+		log.Println("Erreur suppression:", err)
+		renderError(w, r, http.StatusInternalServerError, "La fiche n'a pas pu être supprimée, réessaie dans un instant.")
+		return
 	}
 
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	InvalidateCollectionsCache()
+	urls := slices.Collect(maps.Values(parsePhotoVariants(variantsRaw)))
+	deletePhotoAsync(r.Context(), append(urls, photoURL)...)
+	publishTastingEvent("tasting.deleted", id)
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id})
+		return
+	}
+	http.Redirect(w, r, URLFor("/"), http.StatusSeeOther)
 }
 
 func EditForm(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimSpace(r.URL.Query().Get("id"))
 	if id == "" {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
-	allAromas := GetAromas()
+	allAromas, _ := GetAromas()
 	aMap := aromaMapFromSlice(allAromas)
 
 	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
@@ -488,49 +1113,164 @@ func EditForm(w http.ResponseWriter, r *http.Request) {
 	t, err := scanTasting(row, aMap)
 	if err != nil {
 		log.Println("Erreur lecture:", err)
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
 	data := struct {
 		Tasting Tasting
 		Aromas  []Aroma
-	}{t, allAromas}
+		Error   string
+	}{t, allAromas, strings.TrimSpace(r.URL.Query().Get("error"))}
 
 	if err := Tmpl.ExecuteTemplate(w, "edit.html", data); err != nil {
 		log.Println("Erreur template edit:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+	}
+}
+
+// TastingDetail affiche la fiche complète (lecture seule) d'une dégustation.
+// GET /tasting?id=X
+func TastingDetail(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.URL.Query().Get("id"))
+	if id == "" {
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
+		return
+	}
+
+	aMap := aromaNameMap()
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	row := DB.QueryRowContext(ctx, `SELECT`+tastingSelectCols+`FROM tastings WHERE id = $1`, id)
+	t, err := scanTasting(row, aMap)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("Erreur lecture fiche:", err)
+		}
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
+		return
+	}
+
+	colls, err := collectionsOfTasting(ctx, id)
+	if err != nil {
+		log.Println("Erreur collections de la fiche:", err)
+	}
+
+	neighbors, err := tastingNeighbors(ctx, t)
+	if err != nil {
+		log.Println("Erreur fiches voisines:", err)
 	}
+
+	data := struct {
+		Tasting     Tasting
+		Collections []Collection
+		Neighbors   Neighbors
+	}{t, colls, neighbors}
+
+	if err := Tmpl.ExecuteTemplate(w, "tasting.html", data); err != nil {
+		log.Println("Erreur template tasting:", err)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
+	}
+}
+
+// Neighbors = fiches adjacentes dans l'ordre du journal (created_at DESC).
+// "" en début / fin de journal.
+type Neighbors struct {
+	PrevID string // plus récente
+	NextID string // plus ancienne
+}
+
+// tastingNeighbors cherche les fiches voisines de t ; l'id départage les created_at égaux.
+func tastingNeighbors(ctx context.Context, t Tasting) (Neighbors, error) {
+	var n Neighbors
+
+	err := DB.QueryRowContext(ctx, `SELECT id FROM tastings
+		WHERE (created_at, id) > ($1, $2) AND `+notArchived+`
+		ORDER BY created_at, id LIMIT 1`, t.CreatedAt, t.ID).Scan(&n.PrevID)
+	if err != nil && err != sql.ErrNoRows {
+		return n, err
+	}
+
+	err = DB.QueryRowContext(ctx, `SELECT id FROM tastings
+		WHERE (created_at, id) < ($1, $2) AND `+notArchived+`
+		ORDER BY created_at DESC, id DESC LIMIT 1`, t.CreatedAt, t.ID).Scan(&n.NextID)
+	if err != nil && err != sql.ErrNoRows {
+		return n, err
+	}
+	return n, nil
+}
+
+// TastingNeighborsAPI renvoie les ids précédent / suivant (null aux extrémités).
+// GET /api/v1/tastings/neighbors?id=...
+func TastingNeighborsAPI(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.URL.Query().Get("id"))
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "id manquant")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var t Tasting
+	err := DB.QueryRowContext(ctx, `SELECT id, created_at FROM tastings WHERE id = $1`, id).Scan(&t.ID, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not_found", "dégustation introuvable")
+		return
+	}
+	if err != nil {
+		log.Println("Erreur lecture fiche:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	n, err := tastingNeighbors(ctx, t)
+	if err != nil {
+		log.Println("Erreur fiches voisines:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
+	}
+
+	nullable := func(s string) any {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "prev": nullable(n.PrevID), "next": nullable(n.NextID)})
 }
 
 func UpdateTasting(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
-	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
-		log.Println("Erreur ParseMultipartForm:", err)
-		http.Redirect(w, r, "/", http.StatusFound)
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBody)
+	if !parseUploadForm(w, r) {
+		return
+	}
+	if photoTooLarge(r) {
+		uploadTooLarge(w, r, nil)
 		return
 	}
 
 	id := strings.TrimSpace(r.FormValue("id"))
 	if id == "" {
-		http.Redirect(w, r, "/", http.StatusFound)
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
 		return
 	}
 
-	productName := strings.TrimSpace(r.FormValue("product_name"))
-	maker := strings.TrimSpace(r.FormValue("maker"))
-	city := strings.TrimSpace(r.FormValue("city"))
-
-	mode := strings.TrimSpace(r.FormValue("mode"))
-	if mode == "" {
-		mode = "quick"
+	productName, maker, city, err := validateTastingText(r)
+	if err != nil {
+		http.Redirect(w, r, URLFor("/edit?id="+url.QueryEscape(id)+"&error="+url.QueryEscape(err.Error())), http.StatusFound)
+		return
 	}
 
+	mode := validateMode(r.FormValue("mode"))
+
 	notes := buildNotes(r)
 
 	vueQ := strings.TrimSpace(r.FormValue("vue_quality"))
@@ -538,7 +1278,7 @@ func UpdateTasting(w http.ResponseWriter, r *http.Request) {
 	meltQ := strings.TrimSpace(r.FormValue("melt_quality"))
 	finishL := strings.TrimSpace(r.FormValue("finish_length"))
 
-	if mode != "deep" {
+	if mode != ModeDeep {
 		vueQ, snapQ, meltQ, finishL = "", "", "", ""
 	}
 
@@ -549,54 +1289,233 @@ func UpdateTasting(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	lat := parseFloatOrNull(r.FormValue("latitude"))
-	lng := parseFloatOrNull(r.FormValue("longitude"))
+	warnings := softWarnings(scoreVal, notes, mode, vueQ, snapQ, meltQ, finishL)
 
-	aromaArray := buildPgIntArray(r.Form["aroma_ids"])
+	lat, lng, err := formCoordinates(r)
+	if err != nil {
+		http.Redirect(w, r, URLFor("/edit?id="+url.QueryEscape(id)+"&error="+url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
 
 	{
 		ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
 		defer cancel()
 
-		_, err := DB.ExecContext(ctx, `
-			UPDATE tastings
-			SET product_name=$1, maker=$2, city=$3, score=$4, notes=$5, mode=$6,
-				aroma_ids=$7, latitude=$8, longitude=$9,
-				vue_quality=$10, snap_quality=$11, melt_quality=$12, finish_length=$13
-			WHERE id=$14
-		`,
-			productName, maker, city, scoreVal, notes, mode,
-			aromaArray, lat, lng,
-			vueQ, snapQ, meltQ, finishL,
-			id,
-		)
-
+		var aromasCreated bool
+		err := withTx(ctx, func(tx *sql.Tx) error {
+			aromaArray, created, err := formAromaArray(ctx, tx, r)
+			if err != nil {
+				return fmt.Errorf("arômes libres: %w", err)
+			}
+			aromasCreated = created
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE tastings
+				SET product_name=$1, maker=$2, city=$3, score=$4, notes=$5, mode=$6,
+					aroma_ids=$7, latitude=$8, longitude=$9,
+					vue_quality=$10, snap_quality=$11, melt_quality=$12, finish_length=$13,
+					version=version+1
+				WHERE id=$14
+			`,
+				productName, maker, city, scoreVal, notes, mode,
+				aromaArray, lat, lng,
+				vueQ, snapQ, meltQ, finishL,
+				id,
+			)
+			return err
+		})
 		if err != nil {
 			log.Println("Erreur mise à jour:", err)
-			http.Error(w, "Erreur sauvegarde", http.StatusInternalServerError)
+			renderError(w, r, http.StatusInternalServerError, "La fiche n'a pas pu être enregistrée, réessaie dans un instant.")
 			return
 		}
+		if aromasCreated {
+			InvalidateAromaCache()
+		}
 	}
 
-	// Photo (optionnelle)
-	file, header, err := r.FormFile("photo")
-	if err == nil {
-		defer file.Close()
+	// Photo (optionnelle) : remplacée en arrière-plan, un échec est signalé sans bloquer
+	var warning string
+	photoData, err := readPhotoUpload(r)
+	if err == nil && photoData != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+		defer cancel()
+		err = setPhotoStatus(ctx, id, PhotoPending)
+	}
+	if err != nil {
+		log.Println("Erreur photo:", err)
+		warning = photoUploadFailedMsg
+	} else if photoData != nil {
+		queuePhoto(r, photoJob{TastingID: id, Data: photoData})
+	}
 
-		photoURL, upErr := processAndUploadImage(r.Context(), file, header, id)
-		if upErr != nil {
-			log.Println("Erreur upload photo:", upErr)
-		} else {
-			ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
-			defer cancel()
+	publishTastingEvent("tasting.updated", id)
 
-			if _, upDBErr := DB.ExecContext(ctx, `UPDATE tastings SET photo_url=$1 WHERE id=$2`, photoURL, id); upDBErr != nil {
-				log.Println("Erreur update photo_url:", upDBErr)
+	if wantsJSON(r) {
+		if warnings == nil {
+			warnings = []string{}
+		}
+		resp := map[string]any{"ok": true, "id": id, "warnings": warnings}
+		if warning != "" {
+			resp["warning"] = warning
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	redirectTo := URLFor("/")
+	if msg := savedFlash(warning != "", warnings); msg != "" {
+		redirectTo += "?error=" + url.QueryEscape(msg)
+	}
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+/* ─────────────────────────────────────────────
+   ÉDITION EN LOT (sélection multiple)
+───────────────────────────────────────────── */
+
+// Nombre max de fiches modifiées par requête
+const maxBatchIDs = 500
+
+// batchField : colonne modifiable en lot + validation de la nouvelle valeur.
+type batchField struct {
+	column   string
+	validate func(string) (string, error)
+}
+
+// Champs autorisés (jamais de nom de colonne venant du client)
+var batchFields = map[string]batchField{
+	"city": {"city", func(v string) (string, error) {
+		return validateField("Ville", v, MaxCityLength)
+	}},
+	"maker": {"maker", func(v string) (string, error) {
+		return validateField("Chocolatier", v, MaxMakerLength)
+	}},
+	"mode": {"mode", func(v string) (string, error) {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if !slices.Contains(allowedModes, v) {
+			return v, fmt.Errorf("mode inconnu (valeurs possibles : %s)", strings.Join(allowedModes, ", "))
+		}
+		return v, nil
+	}},
+}
+
+// BatchUpdateField applique une même valeur à un champ de plusieurs fiches, en une transaction.
+// POST /api/v1/tastings/batch  field=city&value=Lyon&ids=1,2,3
+func BatchUpdateField(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "parse error")
+		return
+	}
+
+	field, ok := batchFields[strings.TrimSpace(r.FormValue("field"))]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bad_request", "field doit être city, maker ou mode")
+		return
+	}
+	value, err := field.validate(r.FormValue("value"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	// ids=1,2,3 ou ids=1&ids=2
+	ids := parseIDList(strings.Join(r.Form["ids"], ","))
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "ids requis")
+		return
+	}
+	if len(ids) > maxBatchIDs {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("trop de fiches (max %d)", maxBatchIDs))
+		return
+	}
+
+	// Passage en mode rapide : on vide les qualités, comme à l'ajout
+	set := field.column + ` = $1, version = version + 1`
+	if field.column == "mode" && value != ModeDeep {
+		set += `, vue_quality = '', snap_quality = '', melt_quality = '', finish_length = ''`
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var updated []string
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `UPDATE tastings SET `+set+` WHERE id = ANY($2) RETURNING id`, value, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
 			}
+			updated = append(updated, id)
 		}
+		return rows.Err()
+	})
+	if isInvalidID(err) {
+		writeError(w, http.StatusNotFound, "not_found", "dégustation introuvable (id invalide)")
+		return
+	}
+	if err != nil {
+		log.Println("Erreur édition en lot:", err)
+		writeError(w, http.StatusInternalServerError, "internal", "erreur serveur")
+		return
 	}
 
-	http.Redirect(w, r, "/", http.StatusFound)
+	for _, id := range updated {
+		publishTastingEvent("tasting.updated", id)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":      true,
+		"field":   field.column,
+		"value":   value,
+		"updated": len(updated),
+	})
+}
+
+/* ─────────────────────────────────────────────
+   RANDOM ("surprends-moi")
+───────────────────────────────────────────── */
+
+// RandomTasting redirige vers une dégustation tirée au hasard.
+// GET /random[?collection_id=X]
+func RandomTasting(w http.ResponseWriter, r *http.Request) {
+	collID := strings.TrimSpace(r.URL.Query().Get("collection_id"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+	defer cancel()
+
+	var id string
+	var err error
+	if collID != "" {
+		err = DB.QueryRowContext(ctx, `
+			SELECT t.id
+			FROM tastings t
+			JOIN collection_tastings ct ON ct.tasting_id = t.id
+			WHERE ct.collection_id = $1 AND `+notArchived+`
+			ORDER BY random()
+			LIMIT 1
+		`, collID).Scan(&id)
+	} else {
+		err = DB.QueryRowContext(ctx, `SELECT id FROM tastings WHERE `+notArchived+` ORDER BY random() LIMIT 1`).Scan(&id)
+	}
+
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("Erreur random:", err)
+		}
+		http.Redirect(w, r, URLFor("/"), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, URLFor("/tasting?id="+url.QueryEscape(id)), http.StatusFound)
 }
 
 /* ─────────────────────────────────────────────
@@ -607,15 +1526,15 @@ func MapView(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), dbTimeout)
 	defer cancel()
 
-	rows, err := DB.QueryContext(ctx, `SELECT`+tastingSelectCols+`FROM tastings ORDER BY created_at DESC`)
+	rows, err := DB.QueryContext(ctx, `SELECT`+tastingSelectCols+`FROM tastings WHERE `+notArchived+` ORDER BY created_at DESC`)
 	if err != nil {
 		log.Println("Erreur requête map:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
 	defer rows.Close()
 
-	aMap := aromaMapFromSlice(GetAromas())
+	aMap := aromaNameMap()
 
 	var tastings []Tasting
 	cities := map[string]bool{}
@@ -633,7 +1552,7 @@ func MapView(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := rows.Err(); err != nil {
 		log.Println("Erreur rows map:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
 
@@ -648,7 +1567,7 @@ func MapView(w http.ResponseWriter, r *http.Request) {
 	var buf bytes.Buffer
 	if err := Tmpl.ExecuteTemplate(&buf, "map.html", data); err != nil {
 		log.Println("Erreur template map:", err)
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "Une erreur est survenue, réessaie dans un instant.")
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -656,25 +1575,126 @@ func MapView(w http.ResponseWriter, r *http.Request) {
 }
 
 /* ─────────────────────────────────────────────
-   IMAGE PROCESS + UPLOAD (resize + jpeg)
+   IMAGE PROCESS + UPLOAD (resize + jpeg/webp)
 ───────────────────────────────────────────── */
 
-func processAndUploadImage(ctx context.Context, file multipart.File, header *multipart.FileHeader, tastingID string) (string, error) {
-	supabaseURL := strings.TrimRight(os.Getenv("SUPABASE_URL"), "/")
-	jwtKey := strings.TrimSpace(os.Getenv("SUPABASE_SERVICE_ROLE_KEY"))
-	if supabaseURL == "" || jwtKey == "" {
-		return "", fmt.Errorf("SUPABASE_URL ou SUPABASE_SERVICE_ROLE_KEY manquant")
+// uploadedPhoto = résultat d'un upload : URL publique + métadonnées calculées.
+type uploadedPhoto struct {
+	URL      string
+	BlurHash string // placeholder flouté (vide si le calcul échoue)
+	Color    string // couleur moyenne "#rrggbb"
+	Variants map[int]string
+}
+
+// Largeurs des variantes srcset des cartes (recadrées, <= MaxImageWidth).
+// Seules les largeurs inférieures à celle de la photo sont générées.
+var photoVariantWidths = []int{300, 600}
+
+// parsePhotoVariants lit la colonne jsonb photo_variants ({"300": "url"}).
+func parsePhotoVariants(raw string) map[int]string {
+	var m map[int]string
+	if err := json.Unmarshal([]byte(raw), &m); err != nil || len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// savePhoto enregistre la photo uploadée (URL, métadonnées, variantes) sur la fiche.
+func savePhoto(ctx context.Context, tastingID string, photo uploadedPhoto) error {
+	variants, err := json.Marshal(photo.Variants)
+	if err != nil {
+		return err
+	}
+	_, err = execContext(ctx, "photo.save", `
+		UPDATE tastings SET photo_url=$1, blur_hash=$2, photo_color=$3, photo_variants=$4, photo_status=$5
+		WHERE id=$6
+	`, photo.URL, photo.BlurHash, photo.Color, string(variants), PhotoDone, tastingID)
+	return err
+}
+
+// Srcset construit l'attribut srcset ("url 300w, url 600w, …") ; "" sans variantes.
+func Srcset(variants map[int]string) string {
+	parts := make([]string, 0, len(variants))
+	for _, w := range slices.Sorted(maps.Keys(variants)) {
+		parts = append(parts, fmt.Sprintf("%s %dw", variants[w], w))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Couleur de fond par défaut (fiche sans photo)
+const neutralPhotoColor = "#4a2c1a"
+
+// averageColor calcule la couleur moyenne de l'image en échantillonnant
+// une grille d'environ 64x64 pixels (pas besoin de tout parcourir).
+func averageColor(img image.Image) string {
+	b := img.Bounds()
+	stepX := max(1, b.Dx()/64)
+	stepY := max(1, b.Dy()/64)
+
+	var r, g, bl, n uint64
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r += uint64(cr >> 8)
+			g += uint64(cg >> 8)
+			bl += uint64(cb >> 8)
+			n++
+		}
+	}
+	if n == 0 {
+		return neutralPhotoColor
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r/n, g/n, bl/n)
+}
+
+// BlurHash : 4x3 composantes, calculé sur une miniature (rapide, largement suffisant)
+const (
+	blurHashXComponents = 4
+	blurHashYComponents = 3
+	blurHashSampleWidth = 32
+)
+
+func computeBlurHash(img image.Image) string {
+	small := resize.Resize(blurHashSampleWidth, 0, img, resize.Bilinear)
+	hash, err := blurhash.Encode(blurHashXComponents, blurHashYComponents, small)
+	if err != nil {
+		log.Println("Erreur blurhash:", err)
+		return ""
+	}
+	return hash
+}
+
+// Horloge et aléa des noms de photo (remplaçables pour des noms reproductibles)
+var (
+	nowFunc  = time.Now
+	randFunc = func(b []byte) { _, _ = rand.Read(b) }
+)
+
+// generatePhotoName renvoie la base du nom d'une photo (sans suffixe de variante ni extension) :
+// "tasting-<id>-<horodatage>-<aléa>". L'aléa évite qu'un second upload de la même fiche,
+// dans la même seconde, écrase le premier.
+func generatePhotoName(tastingID string) string {
+	b := make([]byte, 6)
+	randFunc(b)
+	return fmt.Sprintf("tasting-%s-%d-%s", tastingID, nowFunc().Unix(), hex.EncodeToString(b))
+}
+
+func processAndUploadImage(ctx context.Context, src io.Reader, size int64, tastingID string) (uploadedPhoto, error) {
+	var photo uploadedPhoto
+
+	if _, _, err := storageConfig(); err != nil {
+		return photo, err
 	}
 
 	// Petit garde-fou
-	if header != nil && header.Size > MaxUploadSize {
-		return "", fmt.Errorf("fichier trop volumineux (max 10MB)")
+	if size > MaxUploadSize {
+		return photo, fmt.Errorf("fichier trop volumineux (max 10MB)")
 	}
 
 	// Décodage image (jpeg/png/webp si dispo via stdlib: jpeg/png ok; webp non par défaut)
-	img, format, err := image.Decode(file)
+	img, format, err := image.Decode(src)
 	if err != nil {
-		return "", fmt.Errorf("decode image: %w", err)
+		return photo, fmt.Errorf("decode image: %w", err)
 	}
 	_ = format
 
@@ -684,40 +1704,62 @@ func processAndUploadImage(ctx context.Context, file multipart.File, header *mul
 		img = resize.Resize(MaxImageWidth, 0, img, resize.Lanczos3)
 	}
 
-	// Encodage JPEG qualité 80
-	buf := new(bytes.Buffer)
-	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: JpegQuality}); err != nil {
-		return "", fmt.Errorf("encode jpeg: %w", err)
+	// Image de carte : recadrée au centre si PHOTO_MAX_ASPECT est défini
+	card := cropToMaxAspect(img, photoMaxAspect)
+	hasVariants := slices.ContainsFunc(photoVariantWidths, func(w int) bool { return w < card.Bounds().Dx() })
+	if !hasVariants {
+		// Pas de variantes : la photo principale sert aussi de vignette
+		img = card
 	}
 
-	// Nom de fichier : toujours .jpg après compression
-	fileName := fmt.Sprintf("tasting-%s-%d.jpg", tastingID, time.Now().Unix())
-
-	uploadURL := supabaseURL + "/storage/v1/object/photos/" + fileName
+	photo.BlurHash = computeBlurHash(card)
+	photo.Color = averageColor(card)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(buf.Bytes()))
-	if err != nil {
-		return "", err
+	// Encodage au format IMAGE_OUTPUT (JPEG qualité 80 par défaut) puis upload :
+	// photo principale + variantes plus petites pour srcset
+	enc := imageOutput
+	baseName := generatePhotoName(tastingID)
+	upload := func(img image.Image, suffix string) (string, error) {
+		buf := new(bytes.Buffer)
+		if err := enc.Encode(buf, img); err != nil {
+			return "", fmt.Errorf("encode %s: %w", enc.ContentType, err)
+		}
+		// Nom de fichier : extension du format de sortie
+		fileName := baseName + suffix + enc.Ext
+		u, err := uploadStorageObject(ctx, fileName, enc.ContentType, buf.Bytes())
+		if err != nil {
+			return "", fmt.Errorf("upload storage: %w", err)
+		}
+		return u, nil
 	}
 
-	req.Header.Set("Authorization", "Bearer "+jwtKey)
-	req.Header.Set("apikey", jwtKey)
-	req.Header.Set("Content-Type", "image/jpeg")
-	req.Header.Set("x-upsert", "true")
-
-	resp, err := uploadHTTPClient.Do(req)
-	if err != nil {
-		return "", err
+	if photo.URL, err = upload(img, ""); err != nil {
+		return photo, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &httpError{Status: resp.Status, Body: string(body)}
+	// Variantes = image de carte (recadrée) uniquement : la photo principale n'y entre que si
+	// le recadrage ne l'a pas modifiée, sinon une variante carte pleine largeur est ajoutée
+	photo.Variants = map[int]string{}
+	if card.Bounds() == img.Bounds() {
+		photo.Variants[img.Bounds().Dx()] = photo.URL
+	} else if u, err := upload(card, "-card"); err != nil {
+		log.Printf("Erreur variante carte: %v", err)
+	} else {
+		photo.Variants[card.Bounds().Dx()] = u
 	}
 
-	publicURL := supabaseURL + "/storage/v1/object/public/photos/" + fileName
-	return publicURL, nil
+	// Variantes best-effort (depuis l'image de carte) : un échec laisse simplement la photo principale
+	for _, w := range photoVariantWidths {
+		if w >= card.Bounds().Dx() {
+			continue
+		}
+		u, err := upload(resize.Resize(uint(w), 0, card, resize.Lanczos3), fmt.Sprintf("-w%d", w))
+		if err != nil {
+			log.Printf("Erreur variante %dpx: %v", w, err)
+			continue
+		}
+		photo.Variants[w] = u
+	}
+	return photo, nil
 }
 
 /* ─────────────────────────────────────────────
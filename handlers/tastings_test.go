@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateMode(t *testing.T) {
+	saved := DefaultMode
+	DefaultMode = ModeQuick
+	t.Cleanup(func() { DefaultMode = saved })
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ModeQuick},
+		{"quick", ModeQuick},
+		{"deep", ModeDeep},
+		{" DEEP ", ModeDeep},
+		{"Quick", ModeQuick},
+		{"deepp", ModeQuick},
+		{"   ", ModeQuick},
+	}
+	for _, tt := range tests {
+		if got := validateMode(tt.in); got != tt.want {
+			t.Errorf("validateMode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func coordsRequest(lat, lng string, ajax bool) *http.Request {
+	form := url.Values{"latitude": {lat}, "longitude": {lng}}
+	r := httptest.NewRequest(http.MethodPost, "/add", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if ajax {
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+	}
+	return r
+}
+
+func TestParseCoordinates(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng string
+		ok       bool
+		valid    bool
+	}{
+		{"vide", "", "", true, false},
+		{"lyon", "45.76", "4.83", true, true},
+		{"pôle nord, antiméridien est", "90", "180", true, true},
+		{"pôle sud, antiméridien ouest", "-90", "-180", true, true},
+		{"latitude juste au-delà", "90.0001", "0", false, false},
+		{"latitude négative au-delà", "-90.0001", "0", false, false},
+		{"longitude juste au-delà", "0", "180.0001", false, false},
+		{"longitude négative au-delà", "0", "-180.0001", false, false},
+		{"latitude NaN", "NaN", "0", false, false},
+		{"longitude NaN", "0", "NaN", false, false},
+		{"infini", "+Inf", "0", false, false},
+		{"illisible = absente", "abc", "4.83", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lng, err := parseCoordinates(coordsRequest(tt.lat, tt.lng, false))
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, ok attendu %v", err, tt.ok)
+			}
+			if err != nil && (lat.Valid || lng.Valid) {
+				t.Errorf("coordonnées hors limites conservées : %v, %v", lat, lng)
+			}
+			if tt.valid && !(lat.Valid && lng.Valid) {
+				t.Errorf("coordonnées valides perdues : %v, %v", lat, lng)
+			}
+		})
+	}
+}
+
+func TestFormCoordinates(t *testing.T) {
+	// Formulaire classique : l'erreur remonte (formulaire réaffiché)
+	if _, _, err := formCoordinates(coordsRequest("90.0001", "0", false)); !errors.Is(err, errCoordsOutOfRange) {
+		t.Errorf("formulaire : err = %v, want errCoordsOutOfRange", err)
+	}
+	// AJAX : fiche enregistrée sans position
+	lat, lng, err := formCoordinates(coordsRequest("90.0001", "0", true))
+	if err != nil || lat.Valid || lng.Valid {
+		t.Errorf("AJAX : lat=%v lng=%v err=%v, want NULL sans erreur", lat, lng, err)
+	}
+	// Coordonnées valides : identiques dans les deux cas
+	for _, ajax := range []bool{false, true} {
+		lat, lng, err := formCoordinates(coordsRequest("-90", "180", ajax))
+		if err != nil || lat.Float64 != -90 || lng.Float64 != 180 {
+			t.Errorf("ajax=%v : lat=%v lng=%v err=%v", ajax, lat, lng, err)
+		}
+	}
+}
+
+func TestGeneratePhotoNameUnique(t *testing.T) {
+	// Même fiche, même seconde : seul l'aléa distingue les noms
+	savedNow := nowFunc
+	nowFunc = func() time.Time { return time.Unix(1714564800, 0) }
+	t.Cleanup(func() { nowFunc = savedNow })
+
+	const n = 1000
+	seen := make(map[string]bool, n)
+	for range n {
+		name := generatePhotoName("42")
+		if !strings.HasPrefix(name, "tasting-42-1714564800-") {
+			t.Fatalf("nom inattendu : %q", name)
+		}
+		if seen[name] {
+			t.Fatalf("nom en double : %q", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestQuickAddRequiredFields(t *testing.T) {
+	savedPhoto, savedLocation := RequirePhoto, RequireLocation
+	t.Cleanup(func() { RequirePhoto, RequireLocation = savedPhoto, savedLocation })
+
+	for _, tt := range []struct{ photo, location bool }{{true, false}, {false, true}} {
+		RequirePhoto, RequireLocation = tt.photo, tt.location
+		w := httptest.NewRecorder()
+		QuickAdd(w, httptest.NewRequest(http.MethodGet, "/quickadd?product_name=Guanaja", nil))
+		if w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/?error=") {
+			t.Errorf("photo=%v lieu=%v : %d %q, want 303 vers le formulaire complet",
+				tt.photo, tt.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
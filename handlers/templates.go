@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+)
+
+// ─── Chargement des templates ──────────────────────────────────────────────
+
+// RequiredTemplates = templates exécutés par les handlers (et main).
+// Nouvelle page = une ligne ici : un fichier manquant bloque le démarrage
+// au lieu d'une erreur 500 à la première requête.
+var RequiredTemplates = []string{
+	"index.html",
+	"tasting.html",
+	"edit.html",
+	"compare.html",
+	"map.html",
+	"archived.html",
+	"makers.html",
+	"cities.html",
+	"collection.html",
+	"collections_list.html",
+	"quickadd.html",
+	"offline.html",
+	"error.html",
+	"maintenance.html",
+}
+
+// ParseTemplates charge les templates de dir (*.html) avec funcMap, fichier par
+// fichier : toutes les erreurs de syntaxe sont remontées d'un coup, puis les
+// templates de RequiredTemplates absents sont listés.
+func ParseTemplates(dir string, funcMap template.FuncMap) (*template.Template, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := template.New("").Funcs(funcMap)
+	var errs []error
+	for _, f := range files {
+		if _, err := tmpl.ParseFiles(f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("templates invalides :\n%w", errors.Join(errs...))
+	}
+
+	var missing []string
+	for _, name := range RequiredTemplates {
+		if tmpl.Lookup(name) == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("templates manquants dans %s/ : %s", dir, strings.Join(missing, ", "))
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ─── Slash final (URL canonique) ───────────────────────────────────────────
+//
+// Les routes sont enregistrées sans slash final : "/collections/" tombait sur
+// le catch-all "/" (bibliothèque affichée à la place de la page demandée).
+// Seules les routes préfixes ("/static/") gardent leur slash.
+
+// TrailingSlash redirige "/chemin/" vers "/chemin" quand aucune route préfixe
+// de mux ne le couvre. 301 pour GET/HEAD, 308 sinon (méthode et corps conservés).
+// Chemins vus sans BASE_PATH (à monter sous le StripPrefix).
+func TrailingSlash(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if p == "/" || !strings.HasSuffix(p, "/") {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := mux.Handler(r); pattern != "/" && strings.HasSuffix(pattern, "/") {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Un seul slash initial : "//exemple.com/" ne doit pas devenir une URL externe
+		target := "/" + strings.Trim(p, "/")
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, URLFor(target), status)
+	})
+}
@@ -1,8 +1,22 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -10,3 +24,257 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// writeError écrit l'enveloppe d'erreur commune de l'API :
+// {"ok":false,"error":{"code":"not_found","message":"..."}}.
+// code est stable (pour les clients), message lisible (pour l'utilisateur).
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"ok":    false,
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
+
+// errorCode : code d'erreur par défaut d'un statut HTTP (404 -> "not_found").
+func errorCode(status int) string {
+	switch status {
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusInternalServerError:
+		return "internal"
+	case http.StatusBadGateway:
+		return "upstream_error"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	}
+	if text := http.StatusText(status); text != "" {
+		return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+	}
+	return "error"
+}
+
+// isInvalidID : id mal formé pour le type de la colonne (22P02). Les requêtes
+// comparent id = $n (index de clé primaire, pas de id::text) : cette erreur
+// signifie simplement que la fiche n'existe pas.
+func isInvalidID(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "22P02"
+}
+
+// isUniqueViolation : contrainte d'unicité violée (23505), ex : deux écritures
+// concurrentes du même nom d'arôme.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// withTx exécute fn dans une transaction : commit si fn renvoie nil, rollback sinon.
+func withTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// likeEscaper échappe les jokers LIKE/ILIKE (à utiliser avec ESCAPE '\').
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike rend un terme utilisateur littéral dans un motif ILIKE ... ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// parseBoolParam lit un paramètre booléen de formulaire ("1", "true", "on", "oui").
+func parseBoolParam(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "on", "yes", "oui":
+		return true
+	}
+	return false
+}
+
+// parsePositiveInt lit un entier > 0 (page, limite…), sinon def.
+func parsePositiveInt(s string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// parseLimitParam lit le paramètre limit (absent : def), plafonné à max ;
+// erreur s'il n'est pas un entier > 0.
+func parseLimitParam(r *http.Request, def, max int) (int, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get("limit"))
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("limit doit être un entier positif (max %d)", max)
+	}
+	return min(n, max), nil
+}
+
+// FormatScore formate une note : une décimale, sans ".0" final (7.5 / 8).
+func FormatScore(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 1, 64)
+	if len(s) > 2 && s[len(s)-2:] == ".0" {
+		return s[:len(s)-2]
+	}
+	return s
+}
+
+// ─── Locale d'affichage (APP_LOCALE) ───────────────────────────────────────
+
+// Séparateur décimal des nombres affichés (virgule : français par défaut).
+// Les données destinées au JS / aux formulaires restent au format FormatScore.
+var decimalSep = ","
+
+// Langues dont le séparateur décimal est le point (les autres : virgule)
+var pointDecimalLangs = map[string]bool{
+	"en": true, "ja": true, "zh": true, "ko": true, "he": true, "th": true, "hi": true, "ms": true,
+}
+
+// langue[_RÉGION][.encodage][@variante] : fr, fr_FR.UTF-8, en-US…
+var localeRe = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[_-][a-zA-Z0-9]+)*(?:\.[\w-]+)?(?:@\w+)?$`)
+
+// SetAppLocale lit APP_LOCALE ("fr", "fr_FR.UTF-8", "en-US"… ; vide : français).
+// En cas d'erreur, la locale courante est conservée.
+func SetAppLocale(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		decimalSep = ","
+		return nil
+	}
+	if s == "C" || s == "POSIX" {
+		decimalSep = "."
+		return nil
+	}
+	m := localeRe.FindStringSubmatch(s)
+	if m == nil {
+		return fmt.Errorf("APP_LOCALE invalide : %q (ex : fr_FR, en-US)", s)
+	}
+	if pointDecimalLangs[strings.ToLower(m[1])] {
+		decimalSep = "."
+	} else {
+		decimalSep = ","
+	}
+	return nil
+}
+
+// DecimalSep renvoie le séparateur décimal de la locale (pour le JS des templates).
+func DecimalSep() string { return decimalSep }
+
+// DisplayScore formate une note pour l'affichage selon la locale (7,5 / 8).
+func DisplayScore(f float64) string {
+	return strings.Replace(FormatScore(f), ".", decimalSep, 1)
+}
+
+// appLocation renvoie le fuseau de l'app (APP_TIMEZONE, défaut Europe/Paris).
+// Sert pour les bornes de date ("aujourd'hui", "ce jour-là"…).
+func appLocation() *time.Location {
+	name := strings.TrimSpace(os.Getenv("APP_TIMEZONE"))
+	if name == "" {
+		name = "Europe/Paris"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("APP_TIMEZONE invalide (%q), UTC utilisé: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// wantsJSON : la requête attend du JSON (route /api/, fetch avec Accept JSON, ou XHR).
+func wantsJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") ||
+		strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		strings.Contains(r.Header.Get("X-Requested-With"), "XMLHttpRequest")
+}
+
+// renderError affiche une page d'erreur stylée (error.html), ou du JSON pour l'API.
+// L'erreur technique doit être loggée par l'appelant : `message` est montré à l'utilisateur.
+// Une 500 alors que la base ne répond plus devient la page de maintenance (503).
+func renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if status == http.StatusInternalServerError && !dbReachable(r.Context()) {
+		renderMaintenance(w, r)
+		return
+	}
+	if wantsJSON(r) {
+		writeError(w, status, errorCode(status), message)
+		return
+	}
+
+	data := struct {
+		Status     int
+		StatusText string
+		Message    string
+	}{status, http.StatusText(status), message}
+
+	var buf bytes.Buffer
+	if Tmpl == nil || Tmpl.ExecuteTemplate(&buf, "error.html", data) != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// BasePath = préfixe d'hébergement (ex: "/cacao" derrière un reverse proxy), "" à la racine.
+// Initialisé dans main via NormalizeBasePath(os.Getenv("BASE_PATH")).
+var BasePath string
+
+// NormalizeBasePath nettoie BASE_PATH : "cacao/" -> "/cacao", "/" -> "".
+func NormalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// URLFor préfixe un chemin absolu de l'app avec BasePath (redirections, templates).
+func URLFor(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return BasePath + path
+}
+
+// ─── IP client (reverse proxy) ─────────────────────────────────────────────
+
+// TrustProxy (TRUST_PROXY=1, défini par main) : on est derrière un reverse proxy
+// de confiance qui renseigne X-Forwarded-For / X-Real-IP. Sinon ces en-têtes
+// sont ignorés (n'importe quel client peut les forger).
+var TrustProxy bool
+
+// ClientIP renvoie l'IP réelle du client.
+// Avec TrustProxy : dernière IP de X-Forwarded-For (celle ajoutée par notre proxy,
+// les précédentes peuvent venir du client), puis X-Real-IP, puis RemoteAddr.
+func ClientIP(r *http.Request) string {
+	if TrustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			parts := strings.Split(xff, ",")
+			if ip := net.ParseIP(strings.TrimSpace(parts[len(parts)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"vide", "", ""},
+		{"sans joker", "Valrhona", "Valrhona"},
+		{"pourcent", "70%", `70\%`},
+		{"souligné", "grand_cru", `grand\_cru`},
+		{"antislash", `a\b`, `a\\b`},
+		{"antislash avant joker", `\%`, `\\\%`},
+		{"mélange", `100%_pur\cacao`, `100\%\_pur\\cacao`},
+		{"jokers seuls", `%_%`, `\%\_\%`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeLike(tt.in); got != tt.want {
+				t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInvalidID(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"aucune ligne", sql.ErrNoRows, false},
+		{"id mal formé", &pq.Error{Code: "22P02"}, true},
+		{"id mal formé enveloppé", fmt.Errorf("lot: %w", &pq.Error{Code: "22P02"}), true},
+		{"autre erreur SQL", &pq.Error{Code: "23505"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInvalidID(tt.err); got != tt.want {
+				t.Errorf("isInvalidID(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"doublon", &pq.Error{Code: "23505"}, true},
+		{"doublon enveloppé", fmt.Errorf("arôme: %w", &pq.Error{Code: "23505"}), true},
+		{"id mal formé", &pq.Error{Code: "22P02"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUniqueViolation(tt.err); got != tt.want {
+				t.Errorf("isUniqueViolation(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
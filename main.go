@@ -7,33 +7,243 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-// Middleware log simple (utile en dev + prod)
-func loggingMiddleware(next http.Handler) http.Handler {
+// statusRecorder capture le code HTTP pour le log d'accès.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap permet à http.ResponseController (Flush SSE, deadlines) d'atteindre le writer d'origine.
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// parseLogLevel lit LOG_LEVEL (debug/info/warn/error), info par défaut.
+// Ne s'applique qu'au log d'accès : les log.Printf ("Erreur …", avertissements)
+// restent toujours écrits.
+func parseLogLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// parseDurationEnv lit une durée (ex: "45s", "2m") ; absente, invalide ou <= 0 : def.
+func parseDurationEnv(name string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("⚠️ %s invalide (%q), %s utilisé", name, v, def)
+		return def
+	}
+	return d
+}
+
+// Préfixes jamais loggés par défaut (health checks + assets PWA).
+// Surchargeable via LOG_SKIP_PATHS="/health,/static/".
+var defaultLogSkipPaths = []string{"/health", "/static/", "/sw.js", "/manifest.json", "/icon-192.png", "/icon-512.png"}
+
+func parseLogSkipPaths(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return defaultLogSkipPaths
+	}
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Middleware log d'accès (utile en dev + prod), écrit dans logger.
+// sampleRate = N : on ne logge qu'1 requête réussie sur N ; les erreurs (>= 400) toujours.
+// skipPaths : préfixes de chemins (hors BASE_PATH) exclus du log.
+func loggingMiddleware(next http.Handler, logger *slog.Logger, sampleRate int, skipPaths []string) http.Handler {
+	var counter atomic.Uint64
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s", r.Method, r.RequestURI, r.RemoteAddr)
-		next.ServeHTTP(w, r)
+		path := strings.TrimPrefix(r.URL.Path, handlers.BasePath)
+		for _, p := range skipPaths {
+			if strings.HasPrefix(path, p) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case rec.status >= 400:
+			level = slog.LevelWarn
+		case sampleRate > 1 && counter.Add(1)%uint64(sampleRate) != 0:
+			return
+		}
+
+		logger.Log(r.Context(), level, "http",
+			"method", r.Method,
+			"uri", r.RequestURI,
+			"status", rec.status,
+			"duration", time.Since(start).Round(time.Millisecond),
+			"remote", handlers.ClientIP(r),
+			"request_id", handlers.RequestIDFrom(r.Context()),
+		)
 	})
 }
 
+// sslmodes chiffrés acceptés pour une base distante
+var secureSSLModes = map[string]bool{"require": true, "verify-ca": true, "verify-full": true}
+
+func isLocalDBHost(host string) bool {
+	switch host {
+	case "", "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return strings.HasPrefix(host, "/") // socket unix
+}
+
+// withSSLMode vérifie le sslmode du DSN (URL postgres:// ou "clé=valeur").
+// Base distante sans sslmode : sslmode=require est ajouté. Mode non chiffré :
+// erreur si strict, sinon simple avertissement. Renvoie le DSN effectif et son sslmode.
+func withSSLMode(dsn string, strict bool) (string, string, error) {
+	var host, mode string
+	isURL := strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+
+	var u *url.URL
+	if isURL {
+		var err error
+		if u, err = url.Parse(dsn); err != nil {
+			return "", "", fmt.Errorf("SUPABASE_DB_URL invalide: %w", err)
+		}
+		host = u.Hostname()
+		mode = u.Query().Get("sslmode")
+	} else {
+		for _, kv := range strings.Fields(dsn) {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "host":
+				host = strings.Trim(v, "'")
+			case "sslmode":
+				mode = strings.Trim(v, "'")
+			}
+		}
+	}
+
+	if isLocalDBHost(host) {
+		if mode == "" {
+			mode = "défaut pq (require)"
+		}
+		return dsn, mode, nil
+	}
+
+	if mode == "" {
+		mode = "require"
+		if isURL {
+			q := u.Query()
+			q.Set("sslmode", mode)
+			u.RawQuery = q.Encode()
+			dsn = u.String()
+		} else {
+			dsn += " sslmode=" + mode
+		}
+		return dsn, mode, nil
+	}
+
+	if !secureSSLModes[mode] {
+		if strict {
+			return "", "", fmt.Errorf("sslmode=%s refusé pour %s (DB_REQUIRE_SSL=1)", mode, host)
+		}
+		log.Printf("⚠️ sslmode=%s pour %s : connexion possiblement non chiffrée", mode, host)
+	}
+	return dsn, mode, nil
+}
+
 func main() {
 	// Charge .env si présent (en prod, ça peut ne pas exister, et c'est OK)
 	_ = godotenv.Load()
 
+	// --- Logs ---
+	// Logger dédié au log d'accès : un slog.SetDefault ferait passer tous les
+	// log.Printf par ce niveau, et LOG_LEVEL=warn masquerait les erreurs.
+	accessLog := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: parseLogLevel(os.Getenv("LOG_LEVEL")),
+	}))
+
+	// Sous-commande : serve (défaut), export, import
+	cmd, cmdArgs := parseCommand(os.Args[1:])
+	if _, ok := commands[cmd]; !ok && cmd != "serve" {
+		usage()
+		os.Exit(2)
+	}
+
+	logSampleRate := 1
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("LOG_SAMPLE_RATE"))); err == nil && n > 1 {
+		logSampleRate = n
+	}
+
+	// Derrière un reverse proxy : IP client lue dans X-Forwarded-For / X-Real-IP
+	trustProxy := strings.ToLower(strings.TrimSpace(os.Getenv("TRUST_PROXY")))
+	handlers.TrustProxy = trustProxy == "1" || trustProxy == "true"
+
+	// Rate limiting des écritures : RATE_LIMIT_PER_MINUTE=0 pour désactiver
+	{
+		perMinute, burst := handlers.DefaultWriteRatePerMinute, handlers.DefaultWriteBurst
+		if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("RATE_LIMIT_PER_MINUTE"))); err == nil {
+			perMinute = n
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("RATE_LIMIT_BURST"))); err == nil && n > 0 {
+			burst = n
+		}
+		handlers.SetWriteRateLimit(perMinute, burst)
+	}
+
 	// --- DB ---
 	dsn := os.Getenv("SUPABASE_DB_URL")
 	if dsn == "" {
 		log.Fatal("❌ SUPABASE_DB_URL est vide. Mets-la dans .env ou dans tes variables d'environnement.")
 	}
 
+	// SSL obligatoire hors localhost ; DB_REQUIRE_SSL=1 refuse un sslmode non chiffré
+	requireSSL := strings.ToLower(strings.TrimSpace(os.Getenv("DB_REQUIRE_SSL")))
+	dsn, sslMode, err := withSSLMode(dsn, requireSSL == "1" || requireSSL == "true")
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
+	log.Printf("🔐 DB sslmode=%s", sslMode)
+
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		log.Fatal("❌ Erreur connexion DB:", err)
@@ -55,7 +265,125 @@ func main() {
 		}
 	}
 
-	fmt.Println("✅ Connecté à Supabase !")
+	log.Println("✅ Connecté à Supabase !")
+
+	// Sous-commande de maintenance : on s'exécute puis on sort, sans serveur HTTP
+	if cmd != "serve" {
+		handlers.DB = db
+		code := runCommand(cmd, cmdArgs)
+		db.Close()
+		os.Exit(code)
+	}
+
+	// Démo publique : READ_ONLY=1
+	readOnly := strings.ToLower(strings.TrimSpace(os.Getenv("READ_ONLY")))
+	handlers.ReadOnly = readOnly == "1" || readOnly == "true"
+	if handlers.ReadOnly {
+		log.Println("🔒 Mode lecture seule (READ_ONLY)")
+	}
+
+	// Archivage automatique des vieilles fiches : ARCHIVE_AFTER_MONTHS=24 (0 / absent : désactivé)
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("ARCHIVE_AFTER_MONTHS"))); err == nil && n > 0 {
+		handlers.ArchiveAfterMonths = n
+		log.Printf("🗄️ Archivage automatique après %d mois", n)
+	}
+
+	// Apparence de l'app installée (PWA) : APP_NAME, APP_SHORT_NAME, THEME_COLOR, BACKGROUND_COLOR
+	if v := strings.TrimSpace(os.Getenv("APP_NAME")); v != "" {
+		handlers.AppName = v
+	}
+	if v := strings.TrimSpace(os.Getenv("APP_SHORT_NAME")); v != "" {
+		handlers.AppShortName = v
+	}
+	for env, dst := range map[string]*string{"THEME_COLOR": &handlers.ThemeColor, "BACKGROUND_COLOR": &handlers.BackgroundColor} {
+		v := strings.TrimSpace(os.Getenv(env))
+		switch {
+		case v == "":
+		case handlers.ValidColor(v):
+			*dst = v
+		default:
+			log.Printf("⚠️ %s ignoré : couleur invalide %q (attendu #rrggbb)", env, v)
+		}
+	}
+
+	// Timeouts réseau sortants : UPLOAD_TIMEOUT=45s (storage), GEO_TIMEOUT=3s (géocodage, code-barres)
+	handlers.SetHTTPTimeouts(
+		parseDurationEnv("UPLOAD_TIMEOUT", handlers.DefaultUploadTimeout),
+		parseDurationEnv("GEO_TIMEOUT", handlers.DefaultGeoTimeout),
+	)
+
+	// Requêtes SQL lentes journalisées en JSON : SLOW_QUERY_THRESHOLD=200ms (500ms par défaut)
+	handlers.SlowQueryThreshold = parseDurationEnv("SLOW_QUERY_THRESHOLD", handlers.DefaultSlowQueryThreshold)
+
+	// Cache navigateur des fichiers statiques : STATIC_MAX_AGE=1h, STATIC_HASHED_MAX_AGE=8760h (sw.js toujours no-cache)
+	handlers.StaticMaxAge = parseDurationEnv("STATIC_MAX_AGE", handlers.DefaultStaticMaxAge)
+	handlers.StaticHashedMaxAge = parseDurationEnv("STATIC_HASHED_MAX_AGE", handlers.DefaultStaticHashedMaxAge)
+
+	// Mode présélectionné du formulaire d'ajout : DEFAULT_TASTING_MODE=deep (quick par défaut)
+	if err := handlers.SetDefaultMode(os.Getenv("DEFAULT_TASTING_MODE")); err != nil {
+		log.Printf("⚠️ DEFAULT_TASTING_MODE ignoré : %v", err)
+	}
+
+	// Recherche de lieux limitée à certains pays : GEO_COUNTRYCODES=fr,be,ch (monde entier par défaut)
+	if err := handlers.SetGeoCountryCodes(os.Getenv("GEO_COUNTRYCODES")); err != nil {
+		log.Println("⚠️", err, "— recherche sans filtre pays")
+	}
+
+	// Préremplissage par code-barres via Open Food Facts : BARCODE_LOOKUP=1
+	barcode := strings.ToLower(strings.TrimSpace(os.Getenv("BARCODE_LOOKUP")))
+	handlers.BarcodeLookup = barcode == "1" || barcode == "true"
+
+	// Saisie stricte à l'ajout : REQUIRE_PHOTO=1, REQUIRE_LOCATION=1 (optionnels par défaut).
+	// Ajout rapide alors redirigé vers le formulaire complet ; import CSV exempté.
+	requirePhoto := strings.ToLower(strings.TrimSpace(os.Getenv("REQUIRE_PHOTO")))
+	handlers.RequirePhoto = requirePhoto == "1" || requirePhoto == "true"
+	requireLocation := strings.ToLower(strings.TrimSpace(os.Getenv("REQUIRE_LOCATION")))
+	handlers.RequireLocation = requireLocation == "1" || requireLocation == "true"
+
+	// Moyenne pondérée par mode (ex: SCORE_WEIGHT_QUICK=0.5 pour favoriser les fiches approfondies)
+	if f, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("SCORE_WEIGHT_QUICK")), 64); err == nil && f >= 0 {
+		handlers.ScoreWeightQuick = f
+	}
+	if f, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("SCORE_WEIGHT_DEEP")), 64); err == nil && f >= 0 {
+		handlers.ScoreWeightDeep = f
+	}
+
+	// Préfixe d'hébergement (ex: BASE_PATH=/cacao derrière un reverse proxy)
+	handlers.BasePath = handlers.NormalizeBasePath(os.Getenv("BASE_PATH"))
+
+	// Origine publique (sitemap de la démo) : APP_URL=https://cacao.example.org
+	if err := handlers.SetAppURL(os.Getenv("APP_URL")); err != nil {
+		log.Println("⚠️", err, "— origine reconstituée depuis la requête")
+	}
+
+	// Bucket Supabase Storage (photos)
+	if strings.TrimSpace(os.Getenv("SUPABASE_URL")) != "" {
+		bucket, err := handlers.ParseStorageBucket(os.Getenv("SUPABASE_STORAGE_BUCKET"))
+		if err != nil {
+			log.Fatal("❌ ", err)
+		}
+		handlers.StorageBucket = bucket
+	}
+
+	// Format des photos uploadées : IMAGE_OUTPUT=webp (binaire -tags webp), JPEG sinon
+	if err := handlers.SetImageOutput(os.Getenv("IMAGE_OUTPUT")); err != nil {
+		log.Println("⚠️", err, "— JPEG utilisé")
+	}
+
+	// Recadrage des vignettes trop allongées : PHOTO_MAX_ASPECT=2:1 (désactivé par défaut)
+	if err := handlers.SetPhotoMaxAspect(os.Getenv("PHOTO_MAX_ASPECT")); err != nil {
+		log.Println("⚠️", err, "— pas de recadrage")
+	}
+
+	// Agrégation de la heatmap : MAP_HEATMAP_PRECISION=3 (décimales, 2 par défaut)
+	if err := handlers.SetHeatmapPrecision(os.Getenv("MAP_HEATMAP_PRECISION")); err != nil {
+		log.Println("⚠️", err, "— précision par défaut")
+	}
+
+	// Format des nombres affichés : APP_LOCALE=en_US pour 7.5 (français par défaut : 7,5)
+	if err := handlers.SetAppLocale(os.Getenv("APP_LOCALE")); err != nil {
+		log.Println("⚠️", err, "— format français conservé")
+	}
 
 	// --- Templates ---
 	funcMap := template.FuncMap{
@@ -65,18 +393,24 @@ func main() {
 			}
 			return *p
 		},
-		"fmtScore": func(f float64) string {
-			s := strconv.FormatFloat(f, 'f', 1, 64)
-			if len(s) > 2 && s[len(s)-2:] == ".0" {
-				return s[:len(s)-2]
-			}
-			return s
-		},
+		"fmtScore":        handlers.FormatScore,
+		"score":           handlers.DisplayScore,
+		"decimalSep":      handlers.DecimalSep,
+		"urlFor":          handlers.URLFor,
+		"basePath":        func() string { return handlers.BasePath },
+		"readOnly":        func() bool { return handlers.ReadOnly },
+		"srcset":          handlers.Srcset,
+		"familyColor":     handlers.FamilyColor,
+		"barcodeLookup":   func() bool { return handlers.BarcodeLookup },
+		"requirePhoto":    func() bool { return handlers.RequirePhoto },
+		"requireLocation": func() bool { return handlers.RequireLocation },
+		"themeColor":      func() string { return handlers.ThemeColor },
 	}
 
-	tmpl := template.Must(
-		template.New("").Funcs(funcMap).ParseGlob("templates/*.html"),
-	)
+	tmpl, err := handlers.ParseTemplates("templates", funcMap)
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
 
 	handlers.DB = db
 	handlers.Tmpl = tmpl
@@ -85,32 +419,41 @@ func main() {
 	mux := http.NewServeMux()
 
 	// Fichiers statiques PWA
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	mux.Handle("/static/", http.StripPrefix("/static/", handlers.StaticHandler("static")))
 
-	mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/manifest+json")
-		http.ServeFile(w, r, "static/manifest.json")
-	})
+	mux.HandleFunc("/manifest.json", handlers.Manifest)
+	mux.HandleFunc("/sitemap.xml", handlers.Sitemap)
 
 	mux.HandleFunc("/sw.js", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/javascript")
-		w.Header().Set("Service-Worker-Allowed", "/")
-		http.ServeFile(w, r, "static/sw.js")
+		w.Header().Set("Service-Worker-Allowed", handlers.URLFor("/"))
+		handlers.ServeStaticFile(w, r, "static/sw.js")
 	})
 
 	mux.HandleFunc("/icon-192.png", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "static/icon-192.png")
+		handlers.ServeStaticFile(w, r, "static/icon-192.png")
 	})
 	mux.HandleFunc("/icon-512.png", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "static/icon-512.png")
+		handlers.ServeStaticFile(w, r, "static/icon-512.png")
 	})
 
 	// Routes app
 	mux.HandleFunc("/", handlers.Home)
-	mux.HandleFunc("/add", handlers.AddTasting)
-	mux.HandleFunc("/delete", handlers.DeleteTasting)
+	mux.HandleFunc("/add", handlers.RateLimit(handlers.AddTasting))
+	mux.HandleFunc("/quickadd", handlers.RateLimit(handlers.QuickAdd))
+	mux.HandleFunc("/delete", handlers.RateLimit(handlers.DeleteTasting))
+	mux.HandleFunc("/tasting", handlers.TastingDetail)
 	mux.HandleFunc("/edit", handlers.EditForm)
-	mux.HandleFunc("/update", handlers.UpdateTasting)
+	mux.HandleFunc("/update", handlers.RateLimit(handlers.UpdateTasting))
+	mux.HandleFunc("/compare", handlers.Compare)
+	mux.HandleFunc("/random", handlers.RandomTasting)
+	mux.HandleFunc("/archived", handlers.ArchivedList)
+	mux.HandleFunc("/incomplete", handlers.Incomplete)
+	mux.HandleFunc("/makers", handlers.MakersIndex)
+	mux.HandleFunc("/cities", handlers.CitiesIndex)
+	mux.HandleFunc("/archive", handlers.RateLimit(handlers.ArchiveTasting))
+	mux.HandleFunc("/unarchive", handlers.RateLimit(handlers.UnarchiveTasting))
+	mux.HandleFunc("/stats/chart.svg", handlers.StatsChart)
+	mux.HandleFunc("/import/csv", handlers.RateLimit(handlers.ImportCSV))
 
 	mux.HandleFunc("/offline", func(w http.ResponseWriter, r *http.Request) {
 		tmpl.ExecuteTemplate(w, "offline.html", nil)
@@ -119,26 +462,87 @@ func main() {
 	// Collections
 	mux.HandleFunc("/collections", handlers.ListCollections)
 	mux.HandleFunc("/collections/view", handlers.ViewCollection)
-	mux.HandleFunc("/collections/add", handlers.AddCollection)
-	mux.HandleFunc("/collections/addtasting", handlers.AddToCollection)
-	mux.HandleFunc("/collections/remove", handlers.RemoveFromCollection)
-	mux.HandleFunc("/collections/delete", handlers.DeleteCollection)
+	mux.HandleFunc("/collections/add", handlers.RateLimit(handlers.AddCollection))
+	mux.HandleFunc("/collections/addtasting", handlers.RateLimit(handlers.AddToCollection))
+	mux.HandleFunc("/collections/remove", handlers.RateLimit(handlers.RemoveFromCollection))
+	mux.HandleFunc("/collections/delete", handlers.RateLimit(handlers.DeleteCollection))
+	mux.HandleFunc("/collections/copy", handlers.RateLimit(handlers.CopyCollection))
 	mux.HandleFunc("/collections/for", handlers.CollectionsForTasting)
-	mux.HandleFunc("/collections/remove-ajax", handlers.RemoveFromCollectionAJAX)
+	mux.HandleFunc("/collections/remove-ajax", handlers.RateLimit(handlers.RemoveFromCollectionAJAX))
+	mux.HandleFunc("/collections/toggle", handlers.RateLimit(handlers.ToggleCollectionMembership))
+
+	// Live updates (SSE)
+	mux.HandleFunc("/events", handlers.Events)
+
+	// API JSON : /api/v1/* (+ alias /api/* déprécié), en-tête X-API-Version
+	api := func(path string, h http.HandlerFunc) { handlers.HandleAPI(mux, path, h) }
 
 	// Carte
 	mux.HandleFunc("/map", handlers.MapView)
+	api("/map/heatmap", handlers.MapHeatmap)
 
 	// API — autocomplete + geo proxy
-	mux.HandleFunc("/api/products", handlers.ProductSuggest)
-	mux.HandleFunc("/api/geo/search", handlers.GeoSearch)
-	mux.HandleFunc("/api/geo/reverse", handlers.GeoReverse)
+	api("/products", handlers.ProductSuggest)
+	api("/product/barcode", handlers.ProductByBarcode)
+	api("/aromas", handlers.AromaSearch)
+	api("/aromas/wheel", handlers.AromaWheel)
+	api("/aromas/palette", handlers.AromaPalette)
+	api("/aromas/families", handlers.AromaFamilies)
+	api("/geo/search", handlers.GeoSearch)
+	api("/geo/reverse", handlers.GeoReverse)
+
+	// API — dégustations
+	api("/tastings", handlers.TastingList)
+	api("/tastings/near", handlers.NearTastings)
+	api("/tastings/neighbors", handlers.TastingNeighborsAPI)
+	api("/tastings/changes", handlers.TastingChanges)
+	api("/tastings/{id}/photo-status", handlers.PhotoStatus)
+	api("/tastings/{id}/location", handlers.RateLimit(handlers.SetTastingLocation))
+	api("/tastings/batch", handlers.RateLimit(handlers.BatchUpdateField))
+	api("/tastings/{id}", handlers.RateLimit(handlers.PatchTasting))
+	api("/route", handlers.RouteSummary)
+	api("/on-this-day", handlers.OnThisDay)
+	api("/score/suggest", handlers.SuggestScore)
+	api("/stats/families", handlers.FamilyStats)
+
+	// Contrat OpenAPI
+	api("/openapi.json", handlers.OpenAPI)
+
+	// Admin (protégé par ADMIN_TOKEN)
+	mux.HandleFunc("/admin/merge", handlers.RequireAdmin(handlers.MergeValues))
+	mux.HandleFunc("/admin/db/stats", handlers.RequireAdmin(handlers.DBStats))
+	mux.HandleFunc("/admin/aromas/prune", handlers.RequireAdmin(handlers.PruneOrphanAromas))
+	mux.HandleFunc("/admin/aromas/update", handlers.RequireAdmin(handlers.UpdateAroma))
+	mux.HandleFunc("/admin/aromas/merge", handlers.RequireAdmin(handlers.MergeAromas))
+	mux.HandleFunc("/admin/aromas/families/rename", handlers.RequireAdmin(handlers.RenameAromaFamily))
+	mux.HandleFunc("/admin/aromas/families/assign", handlers.RequireAdmin(handlers.AssignAromaFamily))
+	mux.HandleFunc("/admin/storage/orphans", handlers.RequireAdmin(handlers.StorageOrphans))
+	mux.HandleFunc("/admin/storage/orphans/purge", handlers.RequireAdmin(handlers.PurgeStorageOrphans))
+	mux.HandleFunc("/admin/photos/regenerate", handlers.RequireAdmin(handlers.RegeneratePhotos))
+	mux.HandleFunc("/admin/geocode", handlers.RequireAdmin(handlers.GeocodeCities))
 
 	// Petit endpoint de vie (pratique pour tester vite fait)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
+	mux.HandleFunc("/version", versionHandler)
+
+	// "/collections/" -> "/collections" (301) ; "/static/" et les autres routes préfixes intactes
+	var handler http.Handler = handlers.TrailingSlash(mux)
+
+	// Mode démo : écritures bloquées
+	if handlers.ReadOnly {
+		handler = handlers.ReadOnlyGuard(handler)
+	}
+
+	// Sous-répertoire : toutes les routes sont montées sous BASE_PATH
+	if handlers.BasePath != "" {
+		root := http.NewServeMux()
+		root.Handle(handlers.BasePath+"/", http.StripPrefix(handlers.BasePath, handler))
+		root.Handle(handlers.BasePath, http.RedirectHandler(handlers.BasePath+"/", http.StatusMovedPermanently))
+		handler = root
+	}
 
 	// --- Server ---
 	port := os.Getenv("PORT")
@@ -147,16 +551,48 @@ func main() {
 	}
 
 	addr := ":" + port
-	log.Printf("🚀 Serveur sur http://localhost%s", addr)
+	log.Printf("🚀 Serveur %s sur http://localhost%s%s", currentBuildInfo().Version, addr, handlers.URLFor("/"))
 
 	srv := &http.Server{
 		Addr:              addr,
-		Handler:           loggingMiddleware(mux), // ✅ on applique le middleware ici
+		Handler:           handlers.RequestID(loggingMiddleware(handler, accessLog, logSampleRate, parseLogSkipPaths(os.Getenv("LOG_SKIP_PATHS")))), // ✅ on applique le middleware ici
 		ReadHeaderTimeout: 10 * time.Second,
 		ReadTimeout:       30 * time.Second,
 		WriteTimeout:      30 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
 
-	log.Fatal(srv.ListenAndServe())
+	// Arrêt propre (SIGINT/SIGTERM) : stoppe le planificateur puis le serveur
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cleanupInterval := handlers.DefaultCleanupInterval
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("CLEANUP_INTERVAL"))); err == nil && d > 0 {
+		cleanupInterval = d
+	}
+	go handlers.RunScheduler(ctx, cleanupInterval)
+
+	// Photos redimensionnées/envoyées en arrière-plan (PHOTO_WORKERS, 2 par défaut)
+	photoWorkers := handlers.DefaultPhotoWorkers
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("PHOTO_WORKERS"))); err == nil && n > 0 {
+		photoWorkers = n
+	}
+	handlers.StartPhotoWorkers(ctx, photoWorkers)
+
+	// Les requêtes longues (SSE /events) se terminent avec le contexte d'arrêt
+	srv.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Println("Erreur arrêt serveur:", err)
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	log.Println("👋 Serveur arrêté")
 }
@@ -0,0 +1,27 @@
+-- Qualités du mode approfondi : colonnes dédiées uniquement.
+-- Migration one-shot : récupère les qualités recopiées dans `notes`
+-- ("Vue : …", "Cassant : …", "Texture : …", "Finale : …") puis les retire du texte libre.
+
+BEGIN;
+
+UPDATE tastings
+SET vue_quality = COALESCE(NULLIF(vue_quality, ''), substring(notes FROM '(?n)^Vue : ([^\n]*)$'))
+WHERE mode = 'deep' AND notes ~ '(?n)^Vue : ';
+
+UPDATE tastings
+SET snap_quality = COALESCE(NULLIF(snap_quality, ''), substring(notes FROM '(?n)^Cassant : ([^\n]*)$'))
+WHERE mode = 'deep' AND notes ~ '(?n)^Cassant : ';
+
+UPDATE tastings
+SET melt_quality = COALESCE(NULLIF(melt_quality, ''), substring(notes FROM '(?n)^Texture : ([^\n]*)$'))
+WHERE mode = 'deep' AND notes ~ '(?n)^Texture : ';
+
+UPDATE tastings
+SET finish_length = COALESCE(NULLIF(finish_length, ''), substring(notes FROM '(?n)^Finale : ([^\n]*)$'))
+WHERE mode = 'deep' AND notes ~ '(?n)^Finale : ';
+
+UPDATE tastings
+SET notes = btrim(regexp_replace(notes, '(?n)^(Vue|Cassant|Texture|Finale) : [^\n]*\n?', '', 'g'), E' \n')
+WHERE mode = 'deep' AND notes ~ '(?n)^(Vue|Cassant|Texture|Finale) : ';
+
+COMMIT;
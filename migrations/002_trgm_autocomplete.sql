@@ -0,0 +1,10 @@
+-- Autocomplete produits : index trigram pour les recherches ILIKE '%q%'
+-- (product_name / maker), inutilisables par un index B-tree classique.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS tastings_product_name_trgm_idx
+  ON tastings USING gin (product_name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS tastings_maker_trgm_idx
+  ON tastings USING gin (maker gin_trgm_ops);
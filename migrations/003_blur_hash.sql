@@ -0,0 +1,3 @@
+-- Placeholder flou (BlurHash) calculé à l'upload de la photo.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS blur_hash text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Couleur moyenne de la photo ("#rrggbb"), pour les fonds de cartes.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS photo_color text NOT NULL DEFAULT '';
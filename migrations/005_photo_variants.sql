@@ -0,0 +1,4 @@
+-- Variantes redimensionnées de la photo (srcset) : {"300": "https://…", "600": "https://…"}.
+-- La photo principale (photo_url) y figure aussi sous sa largeur réelle.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS photo_variants jsonb NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,16 @@
+-- Recherche insensible aux accents ("creme" trouve "crème").
+-- unaccent() n'est pas IMMUTABLE : wrapper f_unaccent pour pouvoir l'indexer.
+-- search_path : sur Supabase, les extensions vivent dans le schéma "extensions".
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+  SET search_path = public, extensions, pg_catalog
+  AS $$ SELECT unaccent('unaccent'::regdictionary, $1) $$;
+
+CREATE INDEX IF NOT EXISTS tastings_product_name_unaccent_trgm_idx
+  ON tastings USING gin (f_unaccent(product_name) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS tastings_maker_unaccent_trgm_idx
+  ON tastings USING gin (f_unaccent(maker) gin_trgm_ops);
@@ -0,0 +1,6 @@
+-- État du traitement de la photo (redimensionnement + upload en arrière-plan) :
+-- '' = pas de photo, 'pending' = en file, 'done' = en ligne, 'failed' = échec.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS photo_status text NOT NULL DEFAULT '';
+
+UPDATE tastings SET photo_status = 'done' WHERE photo_status = '' AND COALESCE(photo_url, '') <> '';
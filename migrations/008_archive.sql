@@ -0,0 +1,7 @@
+-- Archivage : fiches retirées de la bibliothèque (et des stats par défaut), toujours consultables sur /archived.
+-- archive_exempt : fiche désarchivée à la main, ignorée ensuite par la politique ARCHIVE_AFTER_MONTHS.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS archived boolean NOT NULL DEFAULT false;
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS archive_exempt boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS tastings_active_created_idx ON tastings (created_at DESC) WHERE NOT archived;
@@ -0,0 +1,42 @@
+-- Synchronisation incrémentale (GET /api/v1/tastings/changes?since=…) :
+-- updated_at tenu à jour par trigger (tous les chemins d'écriture, sans toucher aux requêtes),
+-- et une pierre tombale par fiche supprimée pour que les clients la retirent localement.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS updated_at timestamptz;
+UPDATE tastings SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE tastings ALTER COLUMN updated_at SET DEFAULT now();
+ALTER TABLE tastings ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tastings_updated_at_idx ON tastings (updated_at);
+
+CREATE OR REPLACE FUNCTION tastings_touch_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tastings_touch_updated_at ON tastings;
+CREATE TRIGGER tastings_touch_updated_at
+  BEFORE UPDATE ON tastings
+  FOR EACH ROW EXECUTE FUNCTION tastings_touch_updated_at();
+
+CREATE TABLE IF NOT EXISTS tasting_tombstones (
+  id         text PRIMARY KEY,
+  deleted_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS tasting_tombstones_deleted_at_idx ON tasting_tombstones (deleted_at);
+
+CREATE OR REPLACE FUNCTION tastings_record_tombstone() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO tasting_tombstones (id, deleted_at) VALUES (OLD.id::text, now())
+  ON CONFLICT (id) DO UPDATE SET deleted_at = EXCLUDED.deleted_at;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tastings_record_tombstone ON tastings;
+CREATE TRIGGER tastings_record_tombstone
+  AFTER DELETE ON tastings
+  FOR EACH ROW EXECUTE FUNCTION tastings_record_tombstone();
@@ -0,0 +1,7 @@
+-- Concurrence optimiste : version incrémentée par les modifications faites par l'utilisateur
+-- (formulaire d'édition, PATCH, édition en lot, déplacement sur la carte), jamais par le trigger :
+-- les écritures système (photo traitée, archivage auto, géocodage, fusion d'arômes) ne doivent
+-- pas invalider une sauvegarde rapide en cours.
+-- PATCH /api/v1/tastings/{id} avec If-Match: "<version>" échoue en 412 si la fiche a changé entre-temps.
+
+ALTER TABLE tastings ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,30 @@
+-- Un seul arôme par nom, casse ignorée : deux saisies libres simultanées ("Vanille" / "vanille")
+-- ne créent plus de doublon (createAromas : INSERT … ON CONFLICT DO NOTHING puis relecture).
+-- Doublons existants fusionnés d'abord dans le plus ancien (plus petit id), comme /admin/aromas/merge.
+
+UPDATE tastings t
+SET aroma_ids = (
+  SELECT COALESCE(array_agg(d.aid ORDER BY d.ord), '{}')
+  FROM (
+    SELECT DISTINCT ON (m.aid) m.aid, m.ord
+    FROM (
+      SELECT COALESCE(k.keep_id, u.aid) AS aid, u.ord
+      FROM unnest(t.aroma_ids) WITH ORDINALITY AS u(aid, ord)
+      LEFT JOIN (
+        SELECT id, min(id) OVER (PARTITION BY lower(name)) AS keep_id FROM aromas
+      ) k ON k.id = u.aid
+    ) m
+    ORDER BY m.aid, m.ord
+  ) d
+)
+WHERE EXISTS (
+  SELECT 1 FROM aromas a
+  JOIN aromas b ON lower(b.name) = lower(a.name) AND b.id < a.id
+  WHERE a.id = ANY(t.aroma_ids)
+);
+
+DELETE FROM aromas a
+USING aromas b
+WHERE lower(b.name) = lower(a.name) AND b.id < a.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS aromas_name_lower_key ON aromas (lower(name));
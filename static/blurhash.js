@@ -0,0 +1,70 @@
+// Cacao — décodeur BlurHash minimal
+// Peint les <canvas data-blurhash="…"> (placeholder flou affiché sous la photo
+// le temps qu'elle se charge). Format : https://blurha.sh
+(function () {
+  const CHARS = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~";
+
+  const decode83 = (s) => {
+    let v = 0;
+    for (const c of s) v = v * 83 + CHARS.indexOf(c);
+    return v;
+  };
+  const toLinear = (v) => {
+    v /= 255;
+    return v <= 0.04045 ? v / 12.92 : Math.pow((v + 0.055) / 1.055, 2.4);
+  };
+  const toSRGB = (v) => {
+    v = Math.max(0, Math.min(1, v));
+    return Math.trunc(v <= 0.0031308 ? v * 12.92 * 255 + 0.5 : (1.055 * Math.pow(v, 1 / 2.4) - 0.055) * 255 + 0.5);
+  };
+  const signPow = (v, e) => Math.sign(v) * Math.pow(Math.abs(v), e);
+
+  function decode(hash, w, h) {
+    const size = decode83(hash[0]);
+    const ny = Math.floor(size / 9) + 1;
+    const nx = (size % 9) + 1;
+    if (hash.length !== 4 + 2 * nx * ny) return null;
+
+    const maxAC = (decode83(hash[1]) + 1) / 166;
+    const dc = decode83(hash.substring(2, 6));
+    const colors = [[toLinear(dc >> 16), toLinear((dc >> 8) & 255), toLinear(dc & 255)]];
+    const ac = (q) => signPow((q - 9) / 9, 2) * maxAC;
+    for (let i = 1; i < nx * ny; i++) {
+      const v = decode83(hash.substring(4 + i * 2, 6 + i * 2));
+      colors.push([ac(Math.floor(v / 361)), ac(Math.floor(v / 19) % 19), ac(v % 19)]);
+    }
+
+    const px = new Uint8ClampedArray(w * h * 4);
+    for (let y = 0; y < h; y++) {
+      for (let x = 0; x < w; x++) {
+        let r = 0, g = 0, b = 0;
+        for (let j = 0; j < ny; j++) {
+          for (let i = 0; i < nx; i++) {
+            const basis = Math.cos((Math.PI * x * i) / w) * Math.cos((Math.PI * y * j) / h);
+            const c = colors[i + j * nx];
+            r += c[0] * basis;
+            g += c[1] * basis;
+            b += c[2] * basis;
+          }
+        }
+        const o = 4 * (x + y * w);
+        px[o] = toSRGB(r);
+        px[o + 1] = toSRGB(g);
+        px[o + 2] = toSRGB(b);
+        px[o + 3] = 255;
+      }
+    }
+    return px;
+  }
+
+  function paintAll() {
+    document.querySelectorAll("canvas[data-blurhash]").forEach((cv) => {
+      const px = decode(cv.dataset.blurhash, cv.width, cv.height);
+      if (!px) return;
+      cv.getContext("2d").putImageData(new ImageData(px, cv.width, cv.height), 0, 0);
+    });
+  }
+
+  if (document.readyState === "loading") document.addEventListener("DOMContentLoaded", paintAll);
+  else paintAll();
+})();
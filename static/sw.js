@@ -6,18 +6,21 @@
 // - Assets (images/css/js) : cache-first léger
 // - API / requêtes non-GET : on laisse passer (pas de cache)
 
-const CACHE_NAME = "cacao-v1";
-const OFFLINE_URL = "/offline";
+const CACHE_NAME = "cacao-v3";
+
+// Préfixe d'hébergement (BASE_PATH côté serveur), déduit du scope : "" à la racine, "/cacao" sinon.
+const BASE = new URL(self.registration.scope).pathname.replace(/\/$/, "");
+const OFFLINE_URL = BASE + "/offline";
 
 // Ressources essentielles à mettre en cache au premier chargement.
 // (Important : éviter les URL externes type Google Fonts ici, souvent bloquées par CORS en cache.addAll)
 const PRECACHE_URLS = [
-  "/",
+  BASE + "/",
   OFFLINE_URL,
-  "/manifest.json",
-  "/icon-192.png",
-  "/icon-512.png",
-  "/sw.js",
+  BASE + "/manifest.json",
+  BASE + "/icon-192.png",
+  BASE + "/icon-512.png",
+  BASE + "/sw.js",
 ];
 
 self.addEventListener("install", (event) => {
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+)
+
+// ─── Version du build ──────────────────────────────────────────────────────
+//
+// Injectées au build :
+//
+//	go build -ldflags "-X main.Version=1.4.0 -X main.Commit=$(git rev-parse --short HEAD) -X main.BuildTime=$(date -u +%FT%TZ)"
+//
+// Sans ldflags, Commit et BuildTime viennent des infos VCS embarquées par `go build`.
+var Version, Commit, BuildTime string
+
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+func currentBuildInfo() buildInfo {
+	b := buildInfo{Version: Version, Commit: Commit, BuildTime: BuildTime}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && b.Commit == "":
+				b.Commit = s.Value
+			case s.Key == "vcs.time" && b.BuildTime == "":
+				b.BuildTime = s.Value
+			}
+		}
+	}
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	return b
+}
+
+// versionHandler permet de vérifier quelle révision tourne (non authentifié, rien de sensible).
+// GET /version
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(currentBuildInfo())
+}